@@ -1,6 +1,6 @@
 module github.com/swiftsoftwaregroup/swift-oauth2-client-go
 
-go 1.17
+go 1.21
 
 require (
     // Add any external dependencies here
@@ -0,0 +1,144 @@
+package oauth2client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// APIError is returned when the API responds with an unsuccessful status code.
+//
+// Example:
+//
+//	_, _, err := client.CallAPI(oauth2client.HttpGet, "/users/42", nil, nil)
+//	var apiErr *oauth2client.APIError
+//	if errors.As(err, &apiErr) && apiErr.Problem != nil {
+//		log.Printf("%s: %s", apiErr.Problem.Title, apiErr.Problem.Detail)
+//	}
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header holds the response headers.
+	Header http.Header
+
+	// Body is the raw response body.
+	Body []byte
+
+	// Problem holds the parsed body of an application/problem+json response
+	// (RFC 7807). It is nil for other responses.
+	Problem *ProblemDetails
+}
+
+// Error returns "API call failed with status <code>: <body>".
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API call failed with status %d: %s", e.StatusCode, string(e.Body))
+}
+
+// ProblemDetails is an RFC 7807 problem details object.
+type ProblemDetails struct {
+	// Type is a URI reference identifying the problem type.
+	Type string
+
+	// Title is a short, human-readable summary of the problem type.
+	Title string
+
+	// Status is the HTTP status code set by the server, or 0 if absent.
+	Status int
+
+	// Detail is a human-readable explanation of this occurrence of the problem.
+	Detail string
+
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string
+
+	// Extensions holds any other members of the problem object.
+	Extensions map[string]interface{}
+}
+
+// newAPIError builds an APIError from resp and its already read body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "application/problem+json" {
+		apiErr.Problem = parseProblemDetails(body)
+	}
+	return apiErr
+}
+
+// parseProblemDetails parses an RFC 7807 problem object. Members of the wrong
+// type are ignored, as the RFC requires; it returns nil if body is not a JSON object.
+func parseProblemDetails(body []byte) *ProblemDetails {
+	var members map[string]interface{}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil
+	}
+
+	problem := &ProblemDetails{Type: "about:blank"}
+	for name, value := range members {
+		switch name {
+		case "type":
+			if s, ok := value.(string); ok {
+				problem.Type = s
+			}
+		case "title":
+			if s, ok := value.(string); ok {
+				problem.Title = s
+			}
+		case "status":
+			if n, ok := value.(float64); ok {
+				problem.Status = int(n)
+			}
+		case "detail":
+			if s, ok := value.(string); ok {
+				problem.Detail = s
+			}
+		case "instance":
+			if s, ok := value.(string); ok {
+				problem.Instance = s
+			}
+		default:
+			if problem.Extensions == nil {
+				problem.Extensions = make(map[string]interface{})
+			}
+			problem.Extensions[name] = value
+		}
+	}
+	return problem
+}
+
+// IsNotFound reports whether err is an APIError with status 404 Not Found.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an APIError with status 409 Conflict.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsRetryable reports whether the failed call may succeed if made again: the
+// API responded with 408, 429, 502, 503 or 504, or the request failed with a
+// transient network error.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return err != nil && isTransientError(err)
+}
+
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
@@ -0,0 +1,89 @@
+package oauth2client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	t.Run("Problem details", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":409,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`))
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		_, statusCode, err := client.CallAPI(HttpPost, "/msgs", "hello", nil)
+		if statusCode != http.StatusConflict {
+			t.Errorf("Unexpected status code: %d", statusCode)
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected *APIError, got %T: %v", err, err)
+		}
+		if apiErr.Error() != "API call failed with status 409: "+string(apiErr.Body) {
+			t.Errorf("Unexpected error text: %s", apiErr.Error())
+		}
+		if apiErr.Header.Get("Content-Type") == "" {
+			t.Error("Expected response headers on the error")
+		}
+		problem := apiErr.Problem
+		if problem == nil {
+			t.Fatal("Expected problem details")
+		}
+		if problem.Type != "https://example.com/probs/out-of-credit" || problem.Title != "You do not have enough credit." ||
+			problem.Status != 409 || problem.Detail != "Your current balance is 30, but that costs 50." || problem.Instance != "/account/12345/msgs/abc" {
+			t.Errorf("Unexpected problem details: %+v", problem)
+		}
+		if problem.Extensions["balance"] != float64(30) {
+			t.Errorf("Unexpected extensions: %v", problem.Extensions)
+		}
+		if !IsConflict(err) || IsNotFound(err) || IsRetryable(err) {
+			t.Error("Unexpected classification of 409 error")
+		}
+	})
+
+	t.Run("Plain error body", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no such file", http.StatusNotFound)
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		err := client.DownloadFile(HttpGet, "/file", nil, nil, filepath.Join(t.TempDir(), "file"))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Problem != nil {
+			t.Fatalf("Unexpected error: %#v", err)
+		}
+		if !IsNotFound(err) {
+			t.Error("Expected IsNotFound")
+		}
+	})
+
+	t.Run("Retryable", func(t *testing.T) {
+		tests := []struct {
+			err  error
+			want bool
+		}{
+			{&APIError{StatusCode: http.StatusServiceUnavailable}, true},
+			{fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusTooManyRequests}), true},
+			{&APIError{StatusCode: http.StatusBadRequest}, false},
+			{fmt.Errorf("failed to send request: %w", io.ErrUnexpectedEOF), true},
+			{errors.New("failed to marshal request body"), false},
+			{nil, false},
+		}
+		for _, tt := range tests {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package oauth2client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Authenticator adds credentials to outgoing requests.
+//
+// Authenticate is called for every request the APIClient sends, including
+// retries, and must be safe for concurrent use.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc adapts an ordinary function to the Authenticator interface.
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate calls f(req).
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// credentialRefresher is implemented by authenticators whose credentials can be
+// renewed after the server rejected them with 401 Unauthorized. It reports
+// whether the request is worth sending again.
+type credentialRefresher interface {
+	refreshCredentials(rejected *http.Request) (bool, error)
+}
+
+// TokenSourceAuth authenticates requests with tokens from a TokenSource,
+// sending them in the Authorization header.
+type TokenSourceAuth struct {
+	Source TokenSource
+}
+
+// Authenticate sets the Authorization header to the current token.
+func (a TokenSourceAuth) Authenticate(req *http.Request) error {
+	token, err := a.Source.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get valid token: %w", err)
+	}
+	if token == nil {
+		return errors.New("failed to get valid token: token source returned no token")
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+func (a TokenSourceAuth) refreshCredentials(rejected *http.Request) (bool, error) {
+	refresher, ok := a.Source.(staleTokenRefresher)
+	if !ok {
+		return false, nil
+	}
+	authorization := rejected.Header.Get("Authorization")
+	i := strings.IndexByte(authorization, ' ')
+	if i < 0 {
+		return false, nil
+	}
+	if err := refresher.refreshStaleToken(authorization[i+1:]); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BearerTokenAuth authenticates requests with a static bearer token.
+type BearerTokenAuth struct {
+	Token string
+}
+
+// Authenticate sets the Authorization header to "Bearer <Token>".
+func (a BearerTokenAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// APIKeyLocation specifies where an API key is sent.
+type APIKeyLocation int
+
+// API key locations
+const (
+	APIKeyInHeader APIKeyLocation = iota
+	APIKeyInQuery
+)
+
+// APIKeyAuth authenticates requests with an API key sent in a header or a
+// query parameter.
+//
+// Example:
+//
+//	auth := oauth2client.APIKeyAuth{Name: "X-API-Key", Value: os.Getenv("API_KEY")}
+//	client := oauth2client.NewAPIClientWithAuthenticator(auth, "https://api.example.com")
+type APIKeyAuth struct {
+	// Name is the header or query parameter name.
+	Name string
+
+	// Value is the API key.
+	Value string
+
+	// In selects whether the key is sent as a header (the default) or a query parameter.
+	In APIKeyLocation
+}
+
+// Authenticate adds the API key to req.
+func (a APIKeyAuth) Authenticate(req *http.Request) error {
+	if a.Name == "" {
+		return errors.New("API key name must not be empty")
+	}
+	switch a.In {
+	case APIKeyInHeader:
+		req.Header.Set(a.Name, a.Value)
+	case APIKeyInQuery:
+		req.URL.RawQuery = setQueryParam(req.URL.RawQuery, a.Name, a.Value)
+	default:
+		return fmt.Errorf("unsupported API key location %d", a.In)
+	}
+	return nil
+}
+
+// setQueryParam appends name=value to rawQuery, replacing any existing
+// parameter with the same name. The other parameters are kept as they are,
+// in their original order and encoding.
+func setQueryParam(rawQuery, name, value string) string {
+	var params []string
+	if rawQuery != "" {
+		for _, param := range strings.Split(rawQuery, "&") {
+			key, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if key != name {
+				params = append(params, param)
+			}
+		}
+	}
+	params = append(params, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	return strings.Join(params, "&")
+}
+
+// BasicAuth authenticates requests with HTTP Basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate sets the Authorization header to the Basic credentials.
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// ChainAuthenticators returns an Authenticator that runs each of auths in
+// order. It is typically used to sign a request after another Authenticator
+// has set its Authorization header.
+func ChainAuthenticators(auths ...Authenticator) Authenticator {
+	return authenticatorChain(auths)
+}
+
+type authenticatorChain []Authenticator
+
+func (c authenticatorChain) Authenticate(req *http.Request) error {
+	for _, auth := range c {
+		if err := auth.Authenticate(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c authenticatorChain) refreshCredentials(rejected *http.Request) (bool, error) {
+	refreshed := false
+	for _, auth := range c {
+		refresher, ok := auth.(credentialRefresher)
+		if !ok {
+			continue
+		}
+		ok, err := refresher.refreshCredentials(rejected)
+		if err != nil {
+			return false, err
+		}
+		refreshed = refreshed || ok
+	}
+	return refreshed, nil
+}
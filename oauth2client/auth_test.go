@@ -0,0 +1,69 @@
+package oauth2client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticators(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("X-API-Key") + "|" + r.URL.Query().Get("api_key") + "|" + user + ":" + password))
+	}))
+	defer apiServer.Close()
+
+	tests := []struct {
+		name string
+		auth Authenticator
+		want string
+	}{
+		{"No authentication", nil, "|||:"},
+		{"Bearer token", BearerTokenAuth{Token: "static_token"}, "Bearer static_token|||:"},
+		{"API key header", APIKeyAuth{Name: "X-API-Key", Value: "secret"}, "|secret||:"},
+		{"API key query", APIKeyAuth{Name: "api_key", Value: "secret", In: APIKeyInQuery}, "||secret|:"},
+		{"Basic", BasicAuth{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz|||user:pass"},
+		{"Func", AuthenticatorFunc(func(req *http.Request) error {
+			req.Header.Set("Authorization", "Custom value")
+			return nil
+		}), "Custom value|||:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewAPIClientWithAuthenticator(tt.auth, apiServer.URL)
+			response, _, err := client.CallAPI(HttpGet, "/?page=2", nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(response) != tt.want {
+				t.Errorf("Unexpected credentials: got %q, want %q", response, tt.want)
+			}
+		})
+	}
+
+	t.Run("API key query keeps the query string", func(t *testing.T) {
+		tests := map[string]string{
+			"":                            "api_key=secret",
+			"z=1&a=%7e&sig=x%2By":         "z=1&a=%7e&sig=x%2By&api_key=secret",
+			"api_key=old&b=2&api%5Fkey=3": "b=2&api_key=secret",
+		}
+		auth := APIKeyAuth{Name: "api_key", Value: "secret", In: APIKeyInQuery}
+		for rawQuery, want := range tests {
+			req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/?"+rawQuery, nil)
+			if err := auth.Authenticate(req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if req.URL.RawQuery != want {
+				t.Errorf("Query %q: got %q, want %q", rawQuery, req.URL.RawQuery, want)
+			}
+		}
+	})
+
+	t.Run("API key without name", func(t *testing.T) {
+		client := NewAPIClientWithAuthenticator(APIKeyAuth{Value: "secret"}, apiServer.URL)
+		if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err == nil {
+			t.Error("Expected error for API key without name")
+		}
+	})
+}
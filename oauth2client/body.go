@@ -0,0 +1,104 @@
+package oauth2client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// RequestBody is a streamed request body with an explicit content type and
+// length. Pass a *RequestBody as the body of CallAPI and the other call methods.
+//
+// A RequestBody with GetBody is replayable: it is read again from the start
+// when the request is retried or re-sent after a token refresh, without being
+// buffered in memory. A body that can only be read once is not retried.
+//
+// Example:
+//
+//	body, err := oauth2client.FileBody("./dataset.csv", "text/csv")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	_, _, err = client.CallAPI(oauth2client.HttpPut, "/datasets/42", body, nil)
+type RequestBody struct {
+	// Reader supplies the body for the first request. If nil, GetBody is
+	// called instead.
+	Reader io.Reader
+
+	// GetBody returns a new reader for the whole body. If set, the request can
+	// be sent again after a retryable failure or a 401 Unauthorized response.
+	GetBody func() (io.ReadCloser, error)
+
+	// ContentType is sent as the Content-Type header. Defaults to
+	// "application/octet-stream".
+	ContentType string
+
+	// ContentLength is the length of the body in bytes. If zero or negative,
+	// the length is unknown and the body is sent with chunked encoding.
+	ContentLength int64
+}
+
+// ReaderBody returns a RequestBody that streams r once. Use length -1 if the
+// length of r is not known.
+func ReaderBody(r io.Reader, contentType string, length int64) *RequestBody {
+	return &RequestBody{Reader: r, ContentType: contentType, ContentLength: length}
+}
+
+// ReplayableBody returns a RequestBody that calls getBody for every request sent.
+func ReplayableBody(getBody func() (io.ReadCloser, error), contentType string, length int64) *RequestBody {
+	return &RequestBody{GetBody: getBody, ContentType: contentType, ContentLength: length}
+}
+
+// FileBody returns a replayable RequestBody that streams the file at path,
+// opening it again for every request sent. If contentType is empty,
+// "application/octet-stream" is used.
+func FileBody(path, contentType string) (*RequestBody, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request body file: %w", err)
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("failed to open request body file: %s is a directory", path)
+	}
+	getBody := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	return ReplayableBody(getBody, contentType, fi.Size()), nil
+}
+
+// reader returns the reader for the first request.
+func (b *RequestBody) reader() (io.Reader, error) {
+	if b.Reader != nil {
+		return b.Reader, nil
+	}
+	if b.GetBody == nil {
+		return nil, errors.New("request body has neither Reader nor GetBody")
+	}
+	body, err := b.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request body: %w", err)
+	}
+	return body, nil
+}
+
+// contentType returns the Content-Type header for the body.
+func (b *RequestBody) contentType() string {
+	if b.ContentType == "" {
+		return "application/octet-stream"
+	}
+	return b.ContentType
+}
+
+// apply sets the length and GetBody of req to those of the body.
+func (b *RequestBody) apply(req *http.Request) {
+	if b.GetBody != nil {
+		req.GetBody = b.GetBody
+	}
+	if b.ContentLength > 0 {
+		req.ContentLength = b.ContentLength
+	} else if b.ContentLength < 0 {
+		req.ContentLength = -1
+	}
+}
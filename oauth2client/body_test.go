@@ -0,0 +1,106 @@
+package oauth2client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequestBody(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token_" + string(rune('0'+n)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	type received struct {
+		contentType   string
+		contentLength int64
+		body          string
+	}
+	var requests []received
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{r.Header.Get("Content-Type"), r.ContentLength, string(body)})
+		// Reject the first token of every client to exercise the refresh.
+		if r.Header.Get("Authorization") == "Bearer token_1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+	defer apiServer.Close()
+
+	newClient := func() *APIClient {
+		atomic.StoreInt32(&tokenRequests, 0)
+		requests = nil
+		return NewAPIClient(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}, apiServer.URL)
+	}
+
+	t.Run("File body is replayed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.csv")
+		content := strings.Repeat("a,b,c\n", 1000)
+		os.WriteFile(path, []byte(content), 0600)
+
+		body, err := FileBody(path, "text/csv")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		response, _, err := newClient().CallAPI(HttpPut, "/upload", body, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(response) != content {
+			t.Errorf("Unexpected response length: %d", len(response))
+		}
+		if len(requests) != 2 {
+			t.Fatalf("Expected 2 requests, got %d", len(requests))
+		}
+		for _, r := range requests {
+			if r.contentType != "text/csv" || r.contentLength != int64(len(content)) || r.body != content {
+				t.Errorf("Unexpected request: %s, %d, %d bytes", r.contentType, r.contentLength, len(r.body))
+			}
+		}
+	})
+
+	t.Run("One-shot reader is not replayed", func(t *testing.T) {
+		body := ReaderBody(io.MultiReader(strings.NewReader("streamed")), "text/plain", -1)
+		_, statusCode, err := newClient().CallAPI(HttpPost, "/upload", body, nil)
+		if err == nil || statusCode != http.StatusUnauthorized {
+			t.Errorf("Expected the 401 response, got %d, %v", statusCode, err)
+		}
+		if len(requests) != 1 || requests[0].body != "streamed" || requests[0].contentLength != -1 {
+			t.Errorf("Unexpected requests: %+v", requests)
+		}
+	})
+
+	t.Run("Plain reader", func(t *testing.T) {
+		client := NewAPIClient(nil, apiServer.URL)
+		requests = nil
+		response, _, err := client.CallAPI(HttpPost, "/upload", io.MultiReader(strings.NewReader("raw")), nil)
+		if err != nil || string(response) != "raw" {
+			t.Fatalf("Unexpected result: %s, %v", response, err)
+		}
+		if requests[0].contentType != "application/octet-stream" {
+			t.Errorf("Unexpected content type: %s", requests[0].contentType)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		if _, err := FileBody(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+			t.Error("Expected error for missing file")
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending a request while the circuit
+// breaker for its host is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+// Circuit breaker states
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cooldown has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through to
+	// decide whether to close the circuit again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerSettings configures a circuit breaker. Zero fields take the
+// defaults documented on each field.
+//
+// Example:
+//
+//	client, err := oauth2client.NewAPIClientWithOptions(baseURL,
+//		oauth2client.WithCircuitBreaker(oauth2client.CircuitBreakerSettings{
+//			FailureThreshold: 5,
+//			Cooldown:         30 * time.Second,
+//			OnStateChange: func(name string, from, to oauth2client.CircuitState) {
+//				log.Printf("circuit for %s is now %s", name, to)
+//			},
+//		}),
+//	)
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. Defaults to 5.
+	FailureThreshold int
+
+	// Cooldown is how long the circuit stays open before trial requests are
+	// let through. Defaults to 30 seconds.
+	Cooldown time.Duration
+
+	// HalfOpenRequests is the number of trial requests let through while
+	// half-open; the circuit closes once all of them succeed. Defaults to 1.
+	HalfOpenRequests int
+
+	// IsFailure reports whether a request outcome counts as a failure. By
+	// default, network errors and 5xx responses are failures. A request
+	// canceled by its caller counts as neither a failure nor a success.
+	IsFailure func(resp *http.Response, err error) bool
+
+	// OnStateChange, if set, is called after the circuit for name changes
+	// state. name is the host of the requests the breaker guards.
+	OnStateChange func(name string, from, to CircuitState)
+}
+
+func (s CircuitBreakerSettings) withDefaults() CircuitBreakerSettings {
+	if s.FailureThreshold <= 0 {
+		s.FailureThreshold = 5
+	}
+	if s.Cooldown <= 0 {
+		s.Cooldown = 30 * time.Second
+	}
+	if s.HalfOpenRequests <= 0 {
+		s.HalfOpenRequests = 1
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = isCircuitFailure
+	}
+	return s
+}
+
+// isCircuitFailure is the default CircuitBreakerSettings.IsFailure.
+func isCircuitFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
+
+// circuitBreaker guards the requests to one host.
+type circuitBreaker struct {
+	name     string
+	settings CircuitBreakerSettings
+
+	mutex      sync.Mutex
+	state      CircuitState
+	generation uint64
+	failures   int
+	successes  int
+	inFlight   int
+	openedAt   time.Time
+
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+}
+
+func newCircuitBreaker(name string, settings CircuitBreakerSettings) *circuitBreaker {
+	return &circuitBreaker{name: name, settings: settings.withDefaults(), now: time.Now}
+}
+
+// circuitTransition is a state change to report once the mutex is released.
+type circuitTransition struct {
+	from, to CircuitState
+}
+
+// allow reports whether a request may be sent. If it may, the returned
+// function must be called with the outcome of the request.
+func (b *circuitBreaker) allow() (func(*http.Response, error), error) {
+	b.mutex.Lock()
+	var transitions []circuitTransition
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.Cooldown {
+		transitions = b.setState(CircuitHalfOpen, transitions)
+	}
+
+	var err error
+	switch b.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.inFlight >= b.settings.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			b.inFlight++
+		}
+	}
+	generation := b.generation
+	b.mutex.Unlock()
+	b.notify(transitions)
+
+	if err != nil {
+		return nil, err
+	}
+	return func(resp *http.Response, err error) {
+		if errors.Is(err, context.Canceled) {
+			// The caller gave up; the host's health is unknown.
+			b.release(generation)
+			return
+		}
+		b.record(generation, b.settings.IsFailure(resp, err))
+	}, nil
+}
+
+// release gives back the half-open slot of a request allowed in the given
+// generation without counting its outcome.
+func (b *circuitBreaker) release(generation uint64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if generation == b.generation && b.state == CircuitHalfOpen {
+		b.inFlight--
+	}
+}
+
+// record counts the outcome of a request allowed in the given generation.
+// Outcomes from an earlier generation are ignored.
+func (b *circuitBreaker) record(generation uint64, failed bool) {
+	b.mutex.Lock()
+	var transitions []circuitTransition
+	if generation == b.generation {
+		switch b.state {
+		case CircuitClosed:
+			if !failed {
+				b.failures = 0
+			} else if b.failures++; b.failures >= b.settings.FailureThreshold {
+				transitions = b.setState(CircuitOpen, transitions)
+			}
+		case CircuitHalfOpen:
+			b.inFlight--
+			if failed {
+				transitions = b.setState(CircuitOpen, transitions)
+			} else if b.successes++; b.successes >= b.settings.HalfOpenRequests {
+				transitions = b.setState(CircuitClosed, transitions)
+			}
+		}
+	}
+	b.mutex.Unlock()
+	b.notify(transitions)
+}
+
+// setState moves the breaker to state and starts a new generation. It must be
+// called with the mutex held.
+func (b *circuitBreaker) setState(state CircuitState, transitions []circuitTransition) []circuitTransition {
+	transitions = append(transitions, circuitTransition{from: b.state, to: state})
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.successes = 0
+	b.inFlight = 0
+	if state == CircuitOpen {
+		b.openedAt = b.now()
+	}
+	return transitions
+}
+
+func (b *circuitBreaker) notify(transitions []circuitTransition) {
+	if b.settings.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		b.settings.OnStateChange(b.name, t.from, t.to)
+	}
+}
+
+// circuitBreakers holds one circuit breaker per host.
+type circuitBreakers struct {
+	settings CircuitBreakerSettings
+
+	mutex  sync.Mutex
+	byHost map[string]*circuitBreaker
+}
+
+func (c *circuitBreakers) get(host string) *circuitBreaker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.byHost == nil {
+		c.byHost = make(map[string]*circuitBreaker)
+	}
+	b, ok := c.byHost[host]
+	if !ok {
+		b = newCircuitBreaker(host, c.settings)
+		c.byHost[host] = b
+	}
+	return b
+}
+
+// WithCircuitBreaker guards the API requests to each host with a separate
+// circuit breaker. While the circuit for a host is open, calls fail
+// immediately with ErrCircuitOpen instead of waiting for the host to time out.
+func WithCircuitBreaker(settings CircuitBreakerSettings) ClientOption {
+	return func(o *clientOptions) error {
+		o.circuitBreakers = &circuitBreakers{settings: settings}
+		return nil
+	}
+}
+
+// WithTokenCircuitBreaker guards the token endpoint with its own circuit
+// breaker, so that an unavailable authorization server fails token requests
+// fast without affecting the circuits of the API hosts. It requires
+// WithOAuth2Config.
+func WithTokenCircuitBreaker(settings CircuitBreakerSettings) ClientOption {
+	return func(o *clientOptions) error {
+		o.tokenCircuitBreaker = &settings
+		return nil
+	}
+}
+
+// allowCircuit checks the circuit breaker for the host of req. The returned
+// function must be called with the outcome of the request.
+func (c *APIClient) allowCircuit(req *http.Request) (func(*http.Response, error), error) {
+	if c.circuitBreakers == nil {
+		return func(*http.Response, error) {}, nil
+	}
+	done, err := c.circuitBreakers.get(req.URL.Host).allow()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", req.URL.Host, err)
+	}
+	return done, nil
+}
+
+// CircuitState returns the state of the circuit breaker for host, such as
+// "api.example.com" or "localhost:8080". It returns CircuitClosed if the
+// client has no circuit breaker or has not sent a request to host yet.
+func (c *APIClient) CircuitState(host string) CircuitState {
+	if c.circuitBreakers == nil {
+		return CircuitClosed
+	}
+	c.circuitBreakers.mutex.Lock()
+	b, ok := c.circuitBreakers.byHost[host]
+	c.circuitBreakers.mutex.Unlock()
+	if !ok {
+		return CircuitClosed
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+// tokenEndpointHost returns the host of tokenURL, or tokenURL itself if it
+// cannot be parsed.
+func tokenEndpointHost(tokenURL string) string {
+	if u, err := url.Parse(tokenURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return tokenURL
+}
@@ -0,0 +1,209 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("State machine", func(t *testing.T) {
+		now := time.Unix(1700000000, 0)
+		var transitions []string
+		b := newCircuitBreaker("api.example.com", CircuitBreakerSettings{
+			FailureThreshold: 2,
+			Cooldown:         time.Minute,
+			HalfOpenRequests: 1,
+			OnStateChange: func(name string, from, to CircuitState) {
+				transitions = append(transitions, name+" "+from.String()+"->"+to.String())
+			},
+		})
+		b.now = func() time.Time { return now }
+		failure := &http.Response{StatusCode: http.StatusServiceUnavailable}
+		success := &http.Response{StatusCode: http.StatusOK}
+
+		for i := 0; i < 2; i++ {
+			done, err := b.allow()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			done(failure, nil)
+		}
+		if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+		}
+
+		now = now.Add(time.Minute)
+		probe, err := b.allow()
+		if err != nil {
+			t.Fatalf("Expected a trial request after cooldown, got %v", err)
+		}
+		if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected only one trial request, got %v", err)
+		}
+		probe(failure, nil)
+		if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected the circuit to open again, got %v", err)
+		}
+
+		now = now.Add(time.Minute)
+		probe, _ = b.allow()
+		probe(success, nil)
+		if done, err := b.allow(); err != nil {
+			t.Errorf("Expected the circuit to close, got %v", err)
+		} else {
+			done(success, nil)
+		}
+
+		want := []string{
+			"api.example.com closed->open",
+			"api.example.com open->half-open",
+			"api.example.com half-open->open",
+			"api.example.com open->half-open",
+			"api.example.com half-open->closed",
+		}
+		if strings.Join(transitions, ", ") != strings.Join(want, ", ") {
+			t.Errorf("Unexpected transitions:\n got %v\nwant %v", transitions, want)
+		}
+	})
+
+	t.Run("Canceled trial request", func(t *testing.T) {
+		now := time.Unix(1700000000, 0)
+		b := newCircuitBreaker("api.example.com", CircuitBreakerSettings{FailureThreshold: 1, Cooldown: time.Minute})
+		b.now = func() time.Time { return now }
+		done, _ := b.allow()
+		done(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+
+		now = now.Add(time.Minute)
+		probe, err := b.allow()
+		if err != nil {
+			t.Fatalf("Expected a trial request after cooldown, got %v", err)
+		}
+		probe(nil, fmt.Errorf("failed to send request: %w", context.Canceled))
+		if b.state != CircuitHalfOpen {
+			t.Errorf("Expected the circuit to stay half-open, got %s", b.state)
+		}
+
+		// The slot is free for another trial request.
+		probe, err = b.allow()
+		if err != nil {
+			t.Fatalf("Expected another trial request, got %v", err)
+		}
+		probe(&http.Response{StatusCode: http.StatusOK}, nil)
+		if b.state != CircuitClosed {
+			t.Errorf("Expected the circuit to close, got %s", b.state)
+		}
+	})
+
+	t.Run("Successes reset the failure count", func(t *testing.T) {
+		b := newCircuitBreaker("host", CircuitBreakerSettings{FailureThreshold: 2})
+		for _, status := range []int{500, 200, 500, 200, 500} {
+			done, err := b.allow()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			done(&http.Response{StatusCode: status}, nil)
+		}
+		if b.state != CircuitClosed {
+			t.Errorf("Unexpected state: %s", b.state)
+		}
+	})
+
+	t.Run("Client fails fast", func(t *testing.T) {
+		var requests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 3}))
+		for i := 0; i < 3; i++ {
+			if _, statusCode, _ := client.CallAPI(HttpGet, "/", nil, nil); statusCode != http.StatusBadGateway {
+				t.Fatalf("Unexpected status code: %d", statusCode)
+			}
+		}
+
+		host := strings.TrimPrefix(apiServer.URL, "http://")
+		if state := client.CircuitState(host); state != CircuitOpen {
+			t.Errorf("Unexpected circuit state: %s", state)
+		}
+		_, _, err := client.CallAPI(HttpGet, "/", nil, nil)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+		if n := atomic.LoadInt32(&requests); n != 3 {
+			t.Errorf("Expected 3 requests, got %d", n)
+		}
+
+		var bodies closeCounter
+		if _, _, err := client.CallAPI(HttpPut, "/", bodies.body(), nil); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+		}
+		if opened, closed := bodies.counts(); opened != closed {
+			t.Errorf("Expected every body to be closed, opened %d, closed %d", opened, closed)
+		}
+	})
+
+	t.Run("Token endpoint breaker", func(t *testing.T) {
+		var tokenRequests, apiRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&tokenRequests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer tokenServer.Close()
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&apiRequests, 1)
+		}))
+		defer apiServer.Close()
+
+		var mutex sync.Mutex
+		var opened []string
+		client, err := NewAPIClientWithOptions(apiServer.URL,
+			WithOAuth2Config(OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}),
+			WithCircuitBreaker(CircuitBreakerSettings{FailureThreshold: 1}),
+			WithTokenCircuitBreaker(CircuitBreakerSettings{
+				FailureThreshold: 2,
+				OnStateChange: func(name string, from, to CircuitState) {
+					mutex.Lock()
+					opened = append(opened, name)
+					mutex.Unlock()
+				},
+			}),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			client.CallAPI(HttpGet, "/", nil, nil)
+		}
+		_, _, err = client.CallAPI(HttpGet, "/", nil, nil)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+		if n := atomic.LoadInt32(&tokenRequests); n != 2 {
+			t.Errorf("Expected 2 token requests, got %d", n)
+		}
+		if n := atomic.LoadInt32(&apiRequests); n != 0 {
+			t.Errorf("Expected no API requests, got %d", n)
+		}
+		if len(opened) != 1 || opened[0] != strings.TrimPrefix(tokenServer.URL, "http://") {
+			t.Errorf("Unexpected state changes: %v", opened)
+		}
+		if state := client.CircuitState(strings.TrimPrefix(apiServer.URL, "http://")); state != CircuitClosed {
+			t.Errorf("Expected the API circuit to stay closed, got %s", state)
+		}
+
+		if _, err := NewAPIClientWithOptions(apiServer.URL, WithTokenCircuitBreaker(CircuitBreakerSettings{})); err == nil {
+			t.Error("Expected error for token circuit breaker without OAuth2 configuration")
+		}
+	})
+}
@@ -5,22 +5,34 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"mime"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
+	"time"
 )
 
 // APIClient is a client for making authenticated API calls using OAuth2.
 // It handles token management and provides methods for various types of API requests.
 type APIClient struct {
-	tokenManager *tokenManager
-	baseURL      string
-	httpClient   *http.Client
+	tokenManager   *tokenManager
+	authenticator  Authenticator
+	baseURL        string
+	httpClient     *http.Client
+	defaultHeaders http.Header
+	middleware     []Middleware
+	retryPolicy    *RetryPolicy
+
+	rateLimiter      *RateLimiter
+	pathRateLimiters []pathRateLimiter
+	quota            quotaTracker
+
+	circuitBreakers *circuitBreakers
+
+	statusPolicy StatusPolicy
 }
 
 // NewAPIClient creates a new APIClient with the given OAuth2 configuration and base URL.
@@ -49,16 +61,68 @@ func NewAPIClient(config *OAuth2Config, baseURL string) *APIClient {
 	}
 	if config != nil {
 		client.tokenManager = &tokenManager{config: *config}
+		client.authenticator = TokenSourceAuth{Source: client.tokenManager}
+	}
+	return client
+}
+
+// NewAPIClientWithTokenSource creates a new APIClient that authenticates requests
+// with tokens from source.
+//
+// Parameters:
+//   - source: The TokenSource supplying access tokens. If nil, requests are not authenticated.
+//   - baseURL: The base URL of the API you're accessing.
+//
+// Returns:
+//   - *APIClient: A new instance of APIClient.
+//
+// If the server responds with 401 Unauthorized, the request is retried with a fresh
+// token only when source was obtained from OAuth2Config.TokenSource.
+//
+// Example:
+//
+//	source := oauth2client.StaticTokenSource(&oauth2client.Token{AccessToken: "your_access_token"})
+//	client := oauth2client.NewAPIClientWithTokenSource(source, "https://api.example.com")
+func NewAPIClientWithTokenSource(source TokenSource, baseURL string) *APIClient {
+	if source == nil {
+		return NewAPIClientWithAuthenticator(nil, baseURL)
+	}
+	client := NewAPIClientWithAuthenticator(TokenSourceAuth{Source: source}, baseURL)
+	if tm, ok := source.(*tokenManager); ok {
+		client.tokenManager = tm
 	}
 	return client
 }
 
+// NewAPIClientWithAuthenticator creates a new APIClient that adds credentials to
+// every request using auth.
+//
+// Parameters:
+//   - auth: The Authenticator for outgoing requests, such as BearerTokenAuth, APIKeyAuth
+//     or BasicAuth. If nil, requests are not authenticated.
+//   - baseURL: The base URL of the API you're accessing.
+//
+// Returns:
+//   - *APIClient: A new instance of APIClient.
+//
+// Example:
+//
+//	auth := oauth2client.BasicAuth{Username: "user", Password: "secret"}
+//	client := oauth2client.NewAPIClientWithAuthenticator(auth, "https://api.example.com")
+func NewAPIClientWithAuthenticator(auth Authenticator, baseURL string) *APIClient {
+	return &APIClient{
+		authenticator: auth,
+		baseURL:       baseURL,
+		httpClient:    &http.Client{},
+	}
+}
+
 // CallAPI makes an authenticated API call and returns the response body, status code, and any error.
 //
 // Parameters:
 //   - method: The HTTP method to use (e.g., HttpGet, HttpPost, HttpPut, HttpDelete)
 //   - path: The API endpoint path (will be appended to the base URL)
-//   - body: The request body. Can be nil, a string, []byte, url.Values, or any JSON-serializable type
+//   - body: The request body. Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
 //   - additionalHeaders: Additional HTTP headers to include in the request
 //
 // Returns:
@@ -83,92 +147,7 @@ func NewAPIClient(config *OAuth2Config, baseURL string) *APIClient {
 //	}
 //	fmt.Printf("Status: %d, Response: %s\n", statusCode, string(response))
 func (c *APIClient) CallAPI(method HttpMethod, path string, body interface{}, additionalHeaders map[string]string) ([]byte, int, error) {
-	var token string
-	var err error
-	if c.tokenManager != nil {
-		token, err = c.tokenManager.getValidToken()
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to get valid token: %w", err)
-		}
-	}
-
-	var bodyReader io.Reader
-	var contentType string
-
-	switch v := body.(type) {
-	case nil:
-		// No body
-	case string:
-		bodyReader = strings.NewReader(v)
-		contentType = "text/plain"
-	case []byte:
-		bodyReader = bytes.NewReader(v)
-		contentType = "application/octet-stream"
-	case url.Values:
-		bodyReader = strings.NewReader(v.Encode())
-		contentType = "application/x-www-form-urlencoded"
-	default:
-		jsonBody, err := json.Marshal(v)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(jsonBody)
-		contentType = "application/json"
-	}
-
-	req, err := http.NewRequest(string(method), c.baseURL+path, bodyReader)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
-	}
-
-	for key, value := range additionalHeaders {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
-
-	responseBody, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode == http.StatusUnauthorized && c.tokenManager != nil {
-		// Token might have expired, try refreshing and calling again
-		if err := c.tokenManager.refreshToken(); err != nil {
-			return nil, 0, fmt.Errorf("failed to refresh token: %w", err)
-		}
-		return c.CallAPI(method, path, body, additionalHeaders) // Recursive call with fresh token
-	}
-
-	// Consider both 200 OK and 201 Created as successful responses
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, resp.StatusCode, fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(responseBody))
-	}
-
-	return responseBody, resp.StatusCode, nil
+	return c.CallAPIWithContext(context.Background(), method, path, body, additionalHeaders)
 }
 
 // DownloadFile downloads a file from the specified API endpoint and saves it to the given destination path.
@@ -176,7 +155,7 @@ func (c *APIClient) CallAPI(method HttpMethod, path string, body interface{}, ad
 // Parameters:
 //   - method: The HTTP method to use (typically HttpGet)
 //   - path: The API endpoint path for the file download
-//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, or any JSON-serializable type
+//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
 //   - additionalHeaders: Additional HTTP headers to include in the request
 //   - destPath: The local file path where the downloaded file should be saved
 //
@@ -191,96 +170,7 @@ func (c *APIClient) CallAPI(method HttpMethod, path string, body interface{}, ad
 //	}
 //	fmt.Println("File downloaded successfully")
 func (c *APIClient) DownloadFile(method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, destPath string) error {
-	var token string
-	var err error
-	if c.tokenManager != nil {
-		token, err = c.tokenManager.getValidToken()
-		if err != nil {
-			return fmt.Errorf("failed to get valid token: %w", err)
-		}
-	}
-
-	var bodyReader io.Reader
-	var contentType string
-
-	switch v := body.(type) {
-	case nil:
-		// No body
-	case string:
-		bodyReader = strings.NewReader(v)
-		contentType = "text/plain"
-	case []byte:
-		bodyReader = bytes.NewReader(v)
-		contentType = "application/octet-stream"
-	case url.Values:
-		bodyReader = strings.NewReader(v.Encode())
-		contentType = "application/x-www-form-urlencoded"
-	default:
-		jsonBody, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(jsonBody)
-		contentType = "application/json"
-	}
-
-	req, err := http.NewRequest(string(method), c.baseURL+path, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
-	}
-
-	for key, value := range additionalHeaders {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized && c.tokenManager != nil {
-		if err := c.tokenManager.refreshToken(); err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
-		}
-		return c.DownloadFile(method, path, body, additionalHeaders, destPath)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// If destPath is a directory, try to get filename from Content-Disposition header
-	if fi, err := os.Stat(destPath); err == nil && fi.IsDir() {
-		if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
-			if _, params, err := mime.ParseMediaType(disposition); err == nil {
-				if filename, ok := params["filename"]; ok {
-					destPath = filepath.Join(destPath, filename)
-				}
-			}
-		}
-	}
-
-	out, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
-	}
-
-	return nil
+	return c.DownloadFileWithContext(context.Background(), method, path, body, additionalHeaders, destPath)
 }
 
 // CallAPIWithContext makes an authenticated API call with context and returns the response body, status code, and any error.
@@ -289,7 +179,7 @@ func (c *APIClient) DownloadFile(method HttpMethod, path string, body interface{
 //   - ctx: A context.Context for controlling cancellation and timeouts
 //   - method: The HTTP method to use (e.g., HttpGet, HttpPost, HttpPut, HttpDelete)
 //   - path: The API endpoint path (will be appended to the base URL)
-//   - body: The request body. Can be nil, a string, []byte, url.Values, or any JSON-serializable type
+//   - body: The request body. Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
 //   - additionalHeaders: Additional HTTP headers to include in the request
 //
 // Returns:
@@ -306,93 +196,22 @@ func (c *APIClient) DownloadFile(method HttpMethod, path string, body interface{
 //		log.Fatal(err)
 //	}
 //	fmt.Printf("Status: %d, Response: %s\n", statusCode, string(response))
+//
+// Use CallAPIWithResponse for per-call options such as WithCallStatusPolicy.
 func (c *APIClient) CallAPIWithContext(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string) ([]byte, int, error) {
-	var token string
-	var err error
-	if c.tokenManager != nil {
-		token, err = c.tokenManager.getValidToken()
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to get valid token: %w", err)
-		}
-	}
-
-	var bodyReader io.Reader
-	var contentType string
-
-	switch v := body.(type) {
-	case nil:
-		// No body
-	case string:
-		bodyReader = strings.NewReader(v)
-		contentType = "text/plain"
-	case []byte:
-		bodyReader = bytes.NewReader(v)
-		contentType = "application/octet-stream"
-	case url.Values:
-		bodyReader = strings.NewReader(v.Encode())
-		contentType = "application/x-www-form-urlencoded"
-	default:
-		jsonBody, err := json.Marshal(v)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(jsonBody)
-		contentType = "application/json"
-	}
-
-	req, err := http.NewRequestWithContext(ctx, string(method), c.baseURL+path, bodyReader)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
-	}
-
-	for key, value := range additionalHeaders {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	return c.callAPI(ctx, method, path, body, additionalHeaders, nil)
+}
 
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
+// callAPI is CallAPIWithContext with per-call options.
+func (c *APIClient) callAPI(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, opts []CallOption) ([]byte, int, error) {
+	response, err := c.CallAPIWithResponse(ctx, method, path, body, additionalHeaders, opts...)
+	if response == nil {
+		return nil, 0, err
 	}
-
-	responseBody, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode == http.StatusUnauthorized && c.tokenManager != nil {
-		// Token might have expired, try refreshing and calling again
-		if err := c.tokenManager.refreshToken(); err != nil {
-			return nil, 0, fmt.Errorf("failed to refresh token: %w", err)
-		}
-		return c.CallAPIWithContext(ctx, method, path, body, additionalHeaders) // Recursive call with fresh token
-	}
-
-	// Consider both 200 OK and 201 Created as successful responses
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, resp.StatusCode, fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(responseBody))
+		return nil, response.StatusCode, err
 	}
-
-	return responseBody, resp.StatusCode, nil
+	return response.Body, response.StatusCode, nil
 }
 
 // DownloadFileWithContext downloads a file from the specified API endpoint with context and saves it to the given destination path.
@@ -401,7 +220,7 @@ func (c *APIClient) CallAPIWithContext(ctx context.Context, method HttpMethod, p
 //   - ctx: A context.Context for controlling cancellation and timeouts
 //   - method: The HTTP method to use (typically HttpGet)
 //   - path: The API endpoint path for the file download
-//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, or any JSON-serializable type
+//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
 //   - additionalHeaders: Additional HTTP headers to include in the request
 //   - destPath: The local file path where the downloaded file should be saved
 //
@@ -418,21 +237,58 @@ func (c *APIClient) CallAPIWithContext(ctx context.Context, method HttpMethod, p
 //	}
 //	fmt.Println("File downloaded successfully")
 func (c *APIClient) DownloadFileWithContext(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, destPath string) error {
-	var token string
-	var err error
-	if c.tokenManager != nil {
-		token, err = c.tokenManager.getValidToken()
-		if err != nil {
-			return fmt.Errorf("failed to get valid token: %w", err)
-		}
-	}
+	return c.downloadFile(ctx, method, path, body, additionalHeaders, destPath, nil)
+}
+
+// DownloadFileWithOptions is DownloadFileWithContext with per-call options.
+//
+// Parameters:
+//   - ctx: A context.Context for controlling cancellation and timeouts
+//   - method: The HTTP method to use (typically HttpGet)
+//   - path: The API endpoint path for the file download
+//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
+//   - additionalHeaders: Additional HTTP headers to include in the request
+//   - destPath: The local file path where the downloaded file should be saved
+//   - opts: Per-call options, such as WithProgress, WithExpectedDigest or WithCallStatusPolicy
+//
+// Returns:
+//   - error: Any error that occurred during the download process
+//
+// Example:
+//
+//	err := client.DownloadFileWithOptions(ctx, oauth2client.HttpGet, "/files/document.pdf", nil, nil, "./document.pdf",
+//		oauth2client.WithCallStatusPolicy(oauth2client.AcceptStatusCodes(http.StatusOK, http.StatusPartialContent)))
+func (c *APIClient) DownloadFileWithOptions(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, destPath string, opts ...CallOption) error {
+	return c.downloadFile(ctx, method, path, body, additionalHeaders, destPath, opts)
+}
+
+// newRequest builds a request for path relative to the base URL, encoding body
+// and applying additionalHeaders.
+func (c *APIClient) newRequest(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string) (*http.Request, error) {
+	return c.newRequestURL(ctx, method, c.baseURL+path, body, additionalHeaders)
+}
 
+// newRequestURL builds a request like newRequest, but for an absolute URL.
+// Callers must make sure that the URL may receive the client's credentials.
+func (c *APIClient) newRequestURL(ctx context.Context, method HttpMethod, rawURL string, body interface{}, additionalHeaders map[string]string) (*http.Request, error) {
 	var bodyReader io.Reader
 	var contentType string
+	var requestBody *RequestBody
 
 	switch v := body.(type) {
 	case nil:
 		// No body
+	case *RequestBody:
+		reader, err := v.reader()
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = reader
+		contentType = v.contentType()
+		requestBody = v
+	case io.Reader:
+		bodyReader = v
+		contentType = "application/octet-stream"
 	case string:
 		bodyReader = strings.NewReader(v)
 		contentType = "text/plain"
@@ -445,20 +301,27 @@ func (c *APIClient) DownloadFileWithContext(ctx context.Context, method HttpMeth
 	default:
 		jsonBody, err := json.Marshal(v)
 		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 		bodyReader = bytes.NewReader(jsonBody)
 		contentType = "application/json"
 	}
 
-	req, err := http.NewRequestWithContext(ctx, string(method), c.baseURL+path, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, string(method), rawURL, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		if closer, ok := bodyReader.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if requestBody != nil {
+		requestBody.apply(req)
 	}
 
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	for key, values := range c.defaultHeaders {
+		req.Header[key] = append([]string(nil), values...)
 	}
+
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
@@ -467,45 +330,174 @@ func (c *APIClient) DownloadFileWithContext(ctx context.Context, method HttpMeth
 		req.Header.Set(key, value)
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	return req, nil
+}
+
+// callStats counts the requests sent for one call.
+type callStats struct {
+	attempts int
+	sent     int
+}
+
+// do sends req, retrying it according to the client's RetryPolicy. If stats is
+// not nil, it is updated with the number of requests sent.
+func (c *APIClient) do(req *http.Request, stats *callStats) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if stats != nil {
+			stats.attempts = attempt
+		}
+		resp, err := c.send(req, stats)
+
+		policy := c.retryPolicy
+		if policy == nil || attempt >= policy.MaxAttempts || !policy.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := policy.delay(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		next, rewindErr := rewindRequest(req)
+		if rewindErr != nil {
+			return resp, err
+		}
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+
+		if err := sleepContext(req.Context(), wait); err != nil {
+			closeRequestBody(next)
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		req = next
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode == http.StatusUnauthorized && c.tokenManager != nil {
-		if err := c.tokenManager.refreshToken(); err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
+// send sends req once with credentials from the client's Authenticator through
+// the client's middleware, waiting for the client's rate limiters and checking
+// its circuit breaker first. If the server rejects the credentials with 401
+// Unauthorized and the Authenticator can refresh them, the request is sent
+// once more with the new credentials.
+func (c *APIClient) send(req *http.Request, stats *callStats) (*http.Response, error) {
+	send := chainMiddleware(c.middleware, c.httpClient.Do)
+	return sendAuthenticated(req, c.authenticator, func(req *http.Request) (*http.Response, error) {
+		if err := c.waitRateLimit(req); err != nil {
+			closeRequestBody(req)
+			return nil, fmt.Errorf("failed to send request: %w", err)
 		}
-		return c.DownloadFileWithContext(ctx, method, path, body, additionalHeaders, destPath)
+		done, err := c.allowCircuit(req)
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+		if stats != nil {
+			stats.sent++
+		}
+		resp, err := send(req)
+		done(resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		c.quota.update(resp, time.Now())
+		return resp, nil
+	})
+}
+
+// rewindRequest returns a copy of req whose body is read again from the start.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
 	}
+	retry.Body = body
+	return retry, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+// hashRequestBodyInto writes the request body to h, leaving the body ready to be sent.
+func hashRequestBodyInto(req *http.Request, h hash.Hash) error {
+	body, err := replayableBody(req)
+	if err != nil {
+		return err
 	}
+	defer body.Close()
+	if _, err := io.Copy(h, body); err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	return nil
+}
 
-	// If destPath is a directory, try to get filename from Content-Disposition header
-	if fi, err := os.Stat(destPath); err == nil && fi.IsDir() {
-		if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
-			if _, params, err := mime.ParseMediaType(disposition); err == nil {
-				if filename, ok := params["filename"]; ok {
-					destPath = filepath.Join(destPath, filename)
-				}
-			}
+// replayableBody returns a fresh reader for the request body. If the body can
+// only be read once, it is buffered into memory and req is updated to send
+// the buffered copy.
+func replayableBody(req *http.Request) (io.ReadCloser, error) {
+	if req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
 		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
 	}
+	return body, nil
+}
 
-	out, err := os.Create(destPath)
+// readResponseBody reads the whole response body, decompressing it if needed.
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	reader, err := responseBodyReader(resp)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		return nil, err
 	}
-	defer out.Close()
+	defer reader.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	responseBody, err := io.ReadAll(reader)
 	if err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return responseBody, nil
+}
 
-	return nil
+// responseBodyReader returns the body of resp, decompressing it if it is
+// gzip-encoded. Closing the reader closes resp.Body.
+func responseBodyReader(resp *http.Response) (io.ReadCloser, error) {
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return &gzipReadCloser{Reader: gz, body: resp.Body}, nil
+	default:
+		return resp.Body, nil
+	}
+}
+
+// gzipReadCloser decompresses a response body and closes it when done.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
+
+// drainAndClose discards the rest of body so the connection can be reused.
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 64<<10))
+	body.Close()
 }
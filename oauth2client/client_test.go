@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -100,6 +101,19 @@ func TestAPIClient(t *testing.T) {
 		}
 	})
 
+	t.Run("Absolute URL as path", func(t *testing.T) {
+		var requests int32
+		otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+		}))
+		defer otherServer.Close()
+
+		client.CallAPI(HttpGet, otherServer.URL+"/api/test", nil, nil)
+		if n := atomic.LoadInt32(&requests); n != 0 {
+			t.Errorf("Expected no request to another host, got %d", n)
+		}
+	})
+
 	// Test DownloadFile
 	t.Run("DownloadFile", func(t *testing.T) {
 		tempFile := t.TempDir() + "/test_download.txt"
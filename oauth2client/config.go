@@ -13,12 +13,17 @@ type OAuth2Config struct {
 
 	// Scopes is a list of requested permission scopes.
 	Scopes []string
+
+	// TokenStore optionally persists tokens between runs. If nil, tokens are
+	// only cached in memory.
+	TokenStore TokenStore
 }
 
 // tokenResponse represents the server's response to a token request.
 type tokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
-	Scope       string `json:"scope"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
 }
@@ -0,0 +1,189 @@
+package oauth2client
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/md5"    // register MD5 for Content-MD5 and WithExpectedDigest
+	_ "crypto/sha1"   // register SHA-1 for WithExpectedDigest
+	_ "crypto/sha256" // register SHA-256 for Content-Digest and WithExpectedDigest
+	_ "crypto/sha512" // register SHA-512 for Content-Digest and WithExpectedDigest
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DigestMismatchError is returned by a download whose body does not match an
+// expected digest, either one passed to WithExpectedDigest or one sent by the
+// server in a Content-Digest, Repr-Digest or Content-MD5 header.
+type DigestMismatchError struct {
+	// Source is the header the expected digest came from, or "expected" for
+	// a digest passed to WithExpectedDigest.
+	Source   string
+	Hash     crypto.Hash
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("%s digest mismatch (%s): expected %x, got %x", e.Hash, e.Source, e.Expected, e.Actual)
+}
+
+type expectedDigest struct {
+	hash crypto.Hash
+	sum  []byte
+}
+
+// WithExpectedDigest verifies that a downloaded file has the digest sum
+// computed with hash, such as crypto.SHA256. It may be given more than once.
+//
+// Downloads are also checked against the Content-Digest and Repr-Digest
+// (sha-256 and sha-512) and Content-MD5 headers, when the server sends them.
+// On a mismatch, DownloadFileWithOptions deletes the downloaded data and
+// returns a *DigestMismatchError. DownloadToWriter returns the same error,
+// but cannot take back what it has written.
+//
+// Example:
+//
+//	sum, _ := hex.DecodeString("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+//	err := client.DownloadFileWithOptions(ctx, oauth2client.HttpGet, "/releases/app.tar.gz", nil, nil, "./app.tar.gz",
+//		oauth2client.WithExpectedDigest(crypto.SHA256, sum))
+//	var mismatch *oauth2client.DigestMismatchError
+//	if errors.As(err, &mismatch) {
+//		log.Fatalf("corrupted download: %v", mismatch)
+//	}
+func WithExpectedDigest(hash crypto.Hash, sum []byte) CallOption {
+	return func(o *callOptions) {
+		o.expectedDigests = append(o.expectedDigests, expectedDigest{hash: hash, sum: sum})
+	}
+}
+
+// digestAlgorithms maps the Content-Digest and Repr-Digest algorithms of
+// RFC 9530 to hash functions.
+var digestAlgorithms = map[string]crypto.Hash{
+	"sha-256": crypto.SHA256,
+	"sha-512": crypto.SHA512,
+}
+
+// digestCheck is one digest to verify.
+type digestCheck struct {
+	source   string
+	hash     crypto.Hash
+	expected []byte
+	h        hash.Hash
+
+	// whole is true if the digest covers the whole representation rather
+	// than the content of this response, which is only part of it for a
+	// resumed download.
+	whole bool
+}
+
+// digestVerifier hashes a response body as it is written and compares it
+// with the expected digests.
+type digestVerifier struct {
+	checks []*digestCheck
+}
+
+// newDigestVerifier returns a verifier for the digests expected by options
+// and those announced in the headers of resp. The headers are not used if
+// the transport has decompressed the body, since they describe the
+// compressed bytes.
+func newDigestVerifier(options *callOptions, resp *http.Response) (*digestVerifier, error) {
+	v := &digestVerifier{}
+	for _, expected := range options.expectedDigests {
+		if !expected.hash.Available() {
+			return nil, fmt.Errorf("hash function %v is not available", expected.hash)
+		}
+		v.add("expected", expected.hash, expected.sum, true)
+	}
+	if resp.Uncompressed {
+		return v, nil
+	}
+	for _, field := range []string{"Content-Digest", "Repr-Digest"} {
+		for _, value := range resp.Header.Values(field) {
+			for algorithm, sum := range parseDigestField(value) {
+				if hash, ok := digestAlgorithms[algorithm]; ok {
+					v.add(field, hash, sum, field == "Repr-Digest")
+				}
+			}
+		}
+	}
+	if value := resp.Header.Get("Content-MD5"); value != "" {
+		if sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err == nil {
+			v.add("Content-MD5", crypto.MD5, sum, false)
+		}
+	}
+	return v, nil
+}
+
+func (v *digestVerifier) add(source string, hash crypto.Hash, sum []byte, whole bool) {
+	v.checks = append(v.checks, &digestCheck{source: source, hash: hash, expected: sum, h: hash.New(), whole: whole})
+}
+
+// Write adds p to every digest.
+func (v *digestVerifier) Write(p []byte) (int, error) {
+	for _, check := range v.checks {
+		check.h.Write(p)
+	}
+	return len(p), nil
+}
+
+// seed adds the first n bytes of the file at path, which a resumed download
+// appends to, to the digests of the whole representation.
+func (v *digestVerifier) seed(path string, n int64) error {
+	var whole []io.Writer
+	for _, check := range v.checks {
+		if check.whole {
+			whole = append(whole, check.h)
+		}
+	}
+	if len(whole) == 0 {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(io.MultiWriter(whole...), f, n)
+	return err
+}
+
+// verify returns a *DigestMismatchError for the first digest that does not match.
+func (v *digestVerifier) verify() error {
+	for _, check := range v.checks {
+		if actual := check.h.Sum(nil); !bytes.Equal(actual, check.expected) {
+			return &DigestMismatchError{Source: check.source, Hash: check.hash, Expected: check.expected, Actual: actual}
+		}
+	}
+	return nil
+}
+
+// parseDigestField parses a Content-Digest or Repr-Digest header such as
+// "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:" into a map from
+// lowercased algorithm to digest. Malformed members are skipped.
+func parseDigestField(value string) map[string][]byte {
+	digests := make(map[string][]byte)
+	for _, member := range strings.Split(value, ",") {
+		algorithm, sum, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok {
+			continue
+		}
+		if i := strings.IndexByte(sum, ';'); i >= 0 {
+			sum = sum[:i]
+		}
+		sum = strings.TrimSpace(sum)
+		if len(sum) < 2 || sum[0] != ':' || sum[len(sum)-1] != ':' {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(sum[1 : len(sum)-1])
+		if err != nil {
+			continue
+		}
+		digests[strings.ToLower(algorithm)] = decoded
+	}
+	return digests
+}
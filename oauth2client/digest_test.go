@@ -0,0 +1,144 @@
+package oauth2client
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadDigest(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	sha256Sum := sha256.Sum256(content)
+	sha512Sum := sha512.Sum512(content)
+	md5Sum := md5.Sum(content)
+
+	var mutex sync.Mutex
+	fail := false
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		if fail {
+			w = &truncatingWriter{ResponseWriter: w, limit: 30000}
+			fail = false
+		}
+		mutex.Unlock()
+
+		body := content
+		switch r.URL.Path {
+		case "/content-digest":
+			w.Header().Set("Content-Digest", "sha-512=:"+base64.StdEncoding.EncodeToString(sha512Sum[:])+":, unknown=:AAAA:")
+		case "/content-md5":
+			w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(md5Sum[:]))
+		case "/corrupted":
+			w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sha256Sum[:])+":")
+			body = append([]byte("X"), content[1:]...)
+		case "/resumable":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sha256Sum[:])+":")
+			if start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("Range"), "bytes="), "-")); err == nil {
+				partSum := sha256.Sum256(content[start:])
+				w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(partSum[:])+":")
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer apiServer.Close()
+
+	client := NewAPIClient(nil, apiServer.URL)
+	ctx := context.Background()
+
+	t.Run("Expected digest", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		if err := client.DownloadFileWithOptions(ctx, HttpGet, "/data.bin", nil, nil, destPath, WithExpectedDigest(crypto.SHA256, sha256Sum[:])); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); !bytes.Equal(data, content) {
+			t.Errorf("Unexpected content: %d bytes", len(data))
+		}
+
+		destPath = filepath.Join(t.TempDir(), "data.bin")
+		err := client.DownloadFileWithOptions(ctx, HttpGet, "/data.bin", nil, nil, destPath, WithExpectedDigest(crypto.SHA256, md5Sum[:]))
+		var mismatch *DigestMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Source != "expected" || mismatch.Hash != crypto.SHA256 || !bytes.Equal(mismatch.Actual, sha256Sum[:]) {
+			t.Fatalf("Expected a digest mismatch, got %v", err)
+		}
+		for _, leftover := range []string{destPath, destPath + ".part"} {
+			if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+				t.Errorf("Expected no file at %s", leftover)
+			}
+		}
+	})
+
+	t.Run("Server digests", func(t *testing.T) {
+		for _, path := range []string{"/content-digest", "/content-md5"} {
+			destPath := filepath.Join(t.TempDir(), "data.bin")
+			if err := client.DownloadFile(HttpGet, path, nil, nil, destPath); err != nil {
+				t.Errorf("%s: unexpected error: %v", path, err)
+			}
+		}
+
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		err := client.DownloadFile(HttpGet, "/corrupted", nil, nil, destPath)
+		var mismatch *DigestMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Source != "Content-Digest" {
+			t.Fatalf("Expected a Content-Digest mismatch, got %v", err)
+		}
+		if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+			t.Error("Expected the corrupted download to be deleted")
+		}
+	})
+
+	t.Run("Resumed download", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		mutex.Lock()
+		fail = true
+		mutex.Unlock()
+		if err := client.DownloadFile(HttpGet, "/resumable", nil, nil, destPath); err == nil {
+			t.Fatal("Expected the interrupted download to fail")
+		}
+
+		err := client.DownloadFileWithOptions(ctx, HttpGet, "/resumable", nil, nil, destPath, WithExpectedDigest(crypto.SHA256, sha256Sum[:]))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); !bytes.Equal(data, content) {
+			t.Errorf("Unexpected content: %d bytes", len(data))
+		}
+	})
+
+	t.Run("Writer target", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := client.DownloadToWriter(ctx, HttpGet, "/corrupted", nil, nil, &buf)
+		var mismatch *DigestMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Errorf("Expected a digest mismatch, got %v", err)
+		}
+	})
+
+	t.Run("Unavailable hash", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		if err := client.DownloadFileWithOptions(ctx, HttpGet, "/data.bin", nil, nil, destPath, WithExpectedDigest(crypto.Hash(0), nil)); err == nil {
+			t.Error("Expected an error for an unavailable hash function")
+		}
+	})
+
+	t.Run("Parse digest field", func(t *testing.T) {
+		digests := parseDigestField(`SHA-256=:AQID:;param=1, sha-512=AQID, md5`)
+		if len(digests) != 1 || !bytes.Equal(digests["sha-256"], []byte{1, 2, 3}) {
+			t.Errorf("Unexpected digests: %v", digests)
+		}
+	})
+}
@@ -0,0 +1,407 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileDownload is the state of a DownloadFile call across attempts.
+//
+// The response is written to destPath + ".part" and renamed to destPath once
+// it is complete. If the response carries a strong ETag or a Last-Modified
+// date, it is kept in destPath + ".part.meta", and an interrupted download
+// resumes from the partial file with Range and If-Range requests, within the
+// same call or a later one.
+type fileDownload struct {
+	client            *APIClient
+	ctx               context.Context
+	method            HttpMethod
+	path              string
+	body              interface{}
+	additionalHeaders map[string]string
+	opts              []CallOption
+	options           callOptions
+
+	// destPath is the final path. If it is a directory, the file name is
+	// taken from the first response.
+	destPath string
+	dir      bool
+
+	// resumable is false for requests that cannot be sent as range requests.
+	resumable bool
+}
+
+// errRangeNotSatisfiable is returned by an attempt whose partial file is not
+// a prefix of the current representation.
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+func (c *APIClient) downloadFile(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, destPath string, opts []CallOption) error {
+	d := &fileDownload{
+		client:            c,
+		ctx:               ctx,
+		method:            method,
+		path:              path,
+		body:              body,
+		additionalHeaders: additionalHeaders,
+		opts:              opts,
+		options:           c.callOptions(opts),
+		destPath:          destPath,
+		resumable:         method == HttpGet && body == nil,
+	}
+	if fi, err := os.Stat(destPath); err == nil && fi.IsDir() {
+		d.dir = true
+	}
+	for key := range additionalHeaders {
+		if strings.EqualFold(key, "Range") {
+			d.resumable = false
+		}
+	}
+	if d.options.headers.Get("Range") != "" {
+		d.resumable = false
+	}
+
+	// Failed requests are retried by c.do. This loop only resumes transfers
+	// that broke off after the response arrived, which c.do cannot retry.
+	policy := c.retryPolicy
+	for attempt := 1; ; attempt++ {
+		interrupted, err := d.attempt()
+		if errors.Is(err, errRangeNotSatisfiable) {
+			// Start over without the partial file, without counting an attempt.
+			d.removePartial()
+			interrupted, err = d.attempt()
+		}
+		if err == nil {
+			return nil
+		}
+		if !interrupted || policy == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		wait, _ := policy.delay(attempt, nil)
+		if sleepErr := sleepContext(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+func (d *fileDownload) partPath() string {
+	return d.destPath + ".part"
+}
+
+func (d *fileDownload) metaPath() string {
+	return d.destPath + ".part.meta"
+}
+
+// removePartial deletes the partial file and its validator.
+func (d *fileDownload) removePartial() {
+	if d.dir {
+		return
+	}
+	os.Remove(d.partPath())
+	os.Remove(d.metaPath())
+}
+
+// partialOffset returns the size of a resumable partial file and the
+// validator to send in If-Range, or 0 if the download must start over.
+func (d *fileDownload) partialOffset() (int64, string) {
+	if d.dir || !d.resumable {
+		return 0, ""
+	}
+	validator, err := os.ReadFile(d.metaPath())
+	if err != nil || len(validator) == 0 {
+		return 0, ""
+	}
+	fi, err := os.Stat(d.partPath())
+	if err != nil || fi.Size() == 0 {
+		return 0, ""
+	}
+	return fi.Size(), string(validator)
+}
+
+// attempt sends one request and writes its response. It reports whether the
+// transfer was interrupted with the partial file left in place to resume from.
+func (d *fileDownload) attempt() (bool, error) {
+	c := d.client
+	req, err := c.newRequest(d.ctx, d.method, d.path, d.body, d.additionalHeaders)
+	if err != nil {
+		return false, err
+	}
+	d.options.applyHeaders(req)
+
+	offset, validator := d.partialOffset()
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		drainAndClose(resp.Body)
+		return false, errRangeNotSatisfiable
+	}
+	if !d.options.statusPolicy(resp.StatusCode) {
+		bodyBytes, _ := readResponseBody(resp)
+		return false, newAPIError(resp, bodyBytes)
+	}
+
+	if d.dir {
+		d.destPath = filepath.Join(d.destPath, responseFileName(resp))
+		d.dir = false
+		d.resumable = false
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			return false, errRangeNotSatisfiable
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0
+		validator = responseValidator(resp)
+		if validator != "" && d.resumable {
+			if err := os.WriteFile(d.metaPath(), []byte(validator), 0600); err != nil {
+				return false, fmt.Errorf("failed to create destination file: %w", err)
+			}
+		} else {
+			os.Remove(d.metaPath())
+			validator = ""
+		}
+	}
+
+	verifier, err := newDigestVerifier(&d.options, resp)
+	if err != nil {
+		return false, err
+	}
+	if flags&os.O_APPEND != 0 {
+		if err := verifier.seed(d.partPath(), offset); err != nil {
+			return false, fmt.Errorf("failed to read partial file: %w", err)
+		}
+	}
+
+	out, err := os.OpenFile(d.partPath(), flags, 0666)
+	if err != nil {
+		return false, fmt.Errorf("failed to create destination file: %w", err)
+	}
+
+	progress := d.options.newProgressWriter(out, offset, responseTotal(resp, offset))
+	_, err = io.Copy(progress, io.TeeReader(resp.Body, verifier))
+	progress.finish()
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if validator == "" {
+			d.removePartial()
+			return false, fmt.Errorf("failed to save file: %w", err)
+		}
+		return true, fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := verifier.verify(); err != nil {
+		d.removePartial()
+		return false, err
+	}
+
+	if err := os.Rename(d.partPath(), d.destPath); err != nil {
+		return false, fmt.Errorf("failed to save file: %w", err)
+	}
+	os.Remove(d.metaPath())
+	return false, nil
+}
+
+// responseFileName returns the file name from the Content-Disposition header
+// of resp, or the last element of the request path if there is none. Any
+// directory part is dropped.
+func responseFileName(resp *http.Response) string {
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			if name := baseFileName(params["filename"]); name != "" {
+				return name
+			}
+		}
+	}
+	if resp.Request != nil {
+		if name := baseFileName(resp.Request.URL.Path); name != "" {
+			return name
+		}
+	}
+	return "download"
+}
+
+// baseFileName returns the last element of name, or "" if it does not name a file.
+func baseFileName(name string) string {
+	base := filepath.Base(filepath.FromSlash(name))
+	switch base {
+	case ".", "..", string(filepath.Separator):
+		return ""
+	}
+	return base
+}
+
+// responseValidator returns a validator for If-Range: a strong ETag or the
+// Last-Modified date. It returns "" if the response has neither.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a Content-Range header
+// such as "bytes 100-199/200".
+func contentRangeStart(contentRange string) (int64, error) {
+	spec := strings.TrimPrefix(contentRange, "bytes ")
+	i := strings.IndexByte(spec, '-')
+	if spec == contentRange || i < 0 {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	return strconv.ParseInt(spec[:i], 10, 64)
+}
+
+// ProgressFunc receives the progress of a download: the number of bytes
+// written so far and the total size, or -1 if the server did not send it.
+type ProgressFunc func(transferred, total int64)
+
+// WithProgress reports the progress of DownloadFileWithOptions and DownloadToWriter calls
+// to fn, at most once per interval and once more when the transfer ends.
+// An interval of zero defaults to 100 milliseconds.
+//
+// Example:
+//
+//	err := client.DownloadFileWithOptions(ctx, oauth2client.HttpGet, "/files/big.iso", nil, nil, "./big.iso",
+//		oauth2client.WithProgress(func(transferred, total int64) {
+//			fmt.Printf("\r%d of %d bytes", transferred, total)
+//		}, time.Second))
+func WithProgress(fn ProgressFunc, interval time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.progress = fn
+		o.progressInterval = interval
+	}
+}
+
+// DownloadToWriter downloads the response body of an API call to w.
+//
+// Parameters:
+//   - ctx: A context.Context for controlling cancellation and timeouts
+//   - method: The HTTP method to use (typically HttpGet)
+//   - path: The API endpoint path for the file download
+//   - body: The request body (if any). Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
+//   - additionalHeaders: Additional HTTP headers to include in the request
+//   - w: The writer that receives the response body
+//   - opts: Per-call options, such as WithProgress, WithExpectedDigest or WithCallStatusPolicy
+//
+// Returns:
+//   - int64: The number of bytes written to w
+//   - error: Any error that occurred during the download
+//
+// Unlike DownloadFile, an interrupted transfer is not resumed, since w cannot
+// be rewound.
+//
+// Example:
+//
+//	var buf bytes.Buffer
+//	n, err := client.DownloadToWriter(ctx, oauth2client.HttpGet, "/files/report.csv", nil, nil, &buf)
+func (c *APIClient) DownloadToWriter(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, w io.Writer, opts ...CallOption) (int64, error) {
+	options := c.callOptions(opts)
+	req, err := c.newRequest(ctx, method, path, body, additionalHeaders)
+	if err != nil {
+		return 0, err
+	}
+	options.applyHeaders(req)
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if !options.statusPolicy(resp.StatusCode) {
+		bodyBytes, _ := readResponseBody(resp)
+		return 0, newAPIError(resp, bodyBytes)
+	}
+
+	verifier, err := newDigestVerifier(&options, resp)
+	if err != nil {
+		return 0, err
+	}
+	progress := options.newProgressWriter(w, 0, responseTotal(resp, 0))
+	n, err := io.Copy(progress, io.TeeReader(resp.Body, verifier))
+	progress.finish()
+	if err != nil {
+		return n, fmt.Errorf("failed to save file: %w", err)
+	}
+	return n, verifier.verify()
+}
+
+// responseTotal returns the size of the whole download: the complete length
+// from Content-Range, or offset plus Content-Length, or -1 if unknown.
+func responseTotal(resp *http.Response, offset int64) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		contentRange := resp.Header.Get("Content-Range")
+		if i := strings.LastIndexByte(contentRange, '/'); i >= 0 {
+			if total, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				return total
+			}
+		}
+	}
+	if resp.ContentLength < 0 {
+		return -1
+	}
+	return offset + resp.ContentLength
+}
+
+// progressWriter counts the bytes written to w and reports them to a ProgressFunc.
+type progressWriter struct {
+	w           io.Writer
+	fn          ProgressFunc
+	interval    time.Duration
+	transferred int64
+	total       int64
+	last        time.Time
+}
+
+// newProgressWriter returns a progressWriter for w that starts counting at
+// offset. Without a ProgressFunc, it only passes writes through.
+func (o *callOptions) newProgressWriter(w io.Writer, offset, total int64) *progressWriter {
+	interval := o.progressInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	return &progressWriter{w: w, fn: o.progress, interval: interval, transferred: offset, total: total}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.transferred += int64(n)
+	if p.fn != nil {
+		if now := time.Now(); now.Sub(p.last) >= p.interval {
+			p.last = now
+			p.fn(p.transferred, p.total)
+		}
+	}
+	return n, err
+}
+
+// finish reports the final progress.
+func (p *progressWriter) finish() {
+	if p.fn != nil {
+		p.fn(p.transferred, p.total)
+	}
+}
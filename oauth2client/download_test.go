@@ -0,0 +1,280 @@
+package oauth2client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// truncatingWriter aborts the response after limit bytes of the body.
+type truncatingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var mutex sync.Mutex
+	var ranges []string
+	failures := 0
+	etag := `"v1"`
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		ranges = append(ranges, r.Header.Get("Range")+"|"+r.Header.Get("If-Range"))
+		fail := failures > 0
+		if fail {
+			failures--
+		}
+		currentETag := etag
+		mutex.Unlock()
+
+		lastModified := modTime
+		switch r.URL.Path {
+		case "/no-validator":
+			lastModified = time.Time{}
+		case "/last-modified":
+		default:
+			w.Header().Set("ETag", currentETag)
+		}
+		if fail {
+			w = &truncatingWriter{ResponseWriter: w, limit: 30000}
+		}
+		http.ServeContent(w, r, "", lastModified, bytes.NewReader(content))
+	}))
+	defer apiServer.Close()
+
+	reset := func(n int, newETag string) {
+		mutex.Lock()
+		ranges = nil
+		failures = n
+		etag = newETag
+		mutex.Unlock()
+	}
+
+	t.Run("Resume in a later call", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		client := NewAPIClient(nil, apiServer.URL)
+
+		reset(1, `"v1"`)
+		if err := client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath); err == nil {
+			t.Fatal("Expected the interrupted download to fail")
+		}
+		if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+			t.Errorf("Expected no file at the destination, got %v", err)
+		}
+		if fi, err := os.Stat(destPath + ".part"); err != nil || fi.Size() != 30000 {
+			t.Fatalf("Expected a 30000 byte partial file, got %v, %v", fi, err)
+		}
+
+		if err := client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); !bytes.Equal(data, content) {
+			t.Errorf("Unexpected content: %d bytes", len(data))
+		}
+		if got := ranges[1]; got != `bytes=30000-|"v1"` {
+			t.Errorf("Unexpected range request: %s", got)
+		}
+		for _, leftover := range []string{destPath + ".part", destPath + ".part.meta"} {
+			if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+				t.Errorf("Expected %s to be removed", leftover)
+			}
+		}
+	})
+
+	t.Run("Resume within a call", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+
+		reset(2, `"v1"`)
+		if err := client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); !bytes.Equal(data, content) {
+			t.Errorf("Unexpected content: %d bytes", len(data))
+		}
+		want := []string{`|`, `bytes=30000-|"v1"`, `bytes=60000-|"v1"`}
+		if strings.Join(ranges, " ") != strings.Join(want, " ") {
+			t.Errorf("Unexpected requests: %q", ranges)
+		}
+	})
+
+	t.Run("Failed requests are retried once per attempt", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(&truncatingWriter{ResponseWriter: w, limit: 30000}, r, "", time.Time{}, bytes.NewReader(content))
+				return
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		NewAPIClient(nil, server.URL).DownloadFile(HttpGet, "/data.bin", nil, nil, destPath)
+
+		atomic.StoreInt32(&requests, 1)
+		client, _ := NewAPIClientWithOptions(server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+		if err := client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath); err == nil {
+			t.Fatal("Expected the download to fail")
+		}
+		if n := atomic.LoadInt32(&requests) - 1; n != 3 {
+			t.Errorf("Expected 3 requests, got %d", n)
+		}
+	})
+
+	t.Run("Changed representation starts over", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		client := NewAPIClient(nil, apiServer.URL)
+
+		reset(1, `"v1"`)
+		client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath)
+		reset(0, `"v2"`)
+		if err := client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); !bytes.Equal(data, content) {
+			t.Errorf("Unexpected content: %d bytes", len(data))
+		}
+	})
+
+	t.Run("Last-Modified validator", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		client := NewAPIClient(nil, apiServer.URL)
+
+		reset(1, `"v1"`)
+		if err := client.DownloadFile(HttpGet, "/last-modified", nil, nil, destPath); err == nil {
+			t.Fatal("Expected the interrupted download to fail")
+		}
+		if data, err := os.ReadFile(destPath + ".part.meta"); err != nil || string(data) != modTime.Format(http.TimeFormat) {
+			t.Errorf("Unexpected validator: %q, %v", data, err)
+		}
+	})
+
+	t.Run("No validator", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		client := NewAPIClient(nil, apiServer.URL)
+
+		reset(1, `"v1"`)
+		if err := client.DownloadFile(HttpGet, "/no-validator", nil, nil, destPath); err == nil {
+			t.Fatal("Expected the interrupted download to fail")
+		}
+		for _, leftover := range []string{destPath, destPath + ".part", destPath + ".part.meta"} {
+			if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+				t.Errorf("Expected no file at %s", leftover)
+			}
+		}
+	})
+
+	t.Run("Directory destination", func(t *testing.T) {
+		dir := t.TempDir()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Disposition", `attachment; filename="../report.pdf"`)
+			w.Write([]byte("report"))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(nil, server.URL)
+		if err := client.DownloadFile(HttpGet, "/files/1", nil, nil, dir); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "report.pdf")); err != nil || string(data) != "report" {
+			t.Errorf("Unexpected file: %q, %v", data, err)
+		}
+	})
+}
+
+func TestDownloadProgress(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	var mutex sync.Mutex
+	fail := false
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		if fail {
+			w = &truncatingWriter{ResponseWriter: w, limit: 30000}
+			fail = false
+		}
+		mutex.Unlock()
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer apiServer.Close()
+
+	client := NewAPIClient(nil, apiServer.URL)
+	type report struct{ transferred, total int64 }
+
+	t.Run("Writer target", func(t *testing.T) {
+		var reports []report
+		var buf bytes.Buffer
+		n, err := client.DownloadToWriter(context.Background(), HttpGet, "/data.bin", nil, nil, &buf,
+			WithProgress(func(transferred, total int64) {
+				reports = append(reports, report{transferred, total})
+			}, time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("Unexpected content: %d bytes", n)
+		}
+		// The first write is reported, later ones are throttled, and the end is always reported.
+		if len(reports) != 2 || reports[1] != (report{int64(len(content)), int64(len(content))}) {
+			t.Errorf("Unexpected progress reports: %v", reports)
+		}
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := client.DownloadToWriter(context.Background(), HttpGet, "/missing", nil, nil, &buf)
+		if !IsNotFound(err) || buf.Len() != 0 {
+			t.Errorf("Expected a 404 error and no output, got %v, %d bytes", err, buf.Len())
+		}
+	})
+
+	t.Run("Resumed file download", func(t *testing.T) {
+		destPath := filepath.Join(t.TempDir(), "data.bin")
+		mutex.Lock()
+		fail = true
+		mutex.Unlock()
+		client.DownloadFile(HttpGet, "/data.bin", nil, nil, destPath)
+
+		var reports []report
+		err := client.DownloadFileWithOptions(context.Background(), HttpGet, "/data.bin", nil, nil, destPath,
+			WithProgress(func(transferred, total int64) {
+				reports = append(reports, report{transferred, total})
+			}, time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(reports) == 0 || reports[0].transferred <= 30000 || reports[0].total != int64(len(content)) {
+			t.Errorf("Expected progress to start after the partial file, got %v", reports)
+		}
+		if last := reports[len(reports)-1]; last.transferred != int64(len(content)) {
+			t.Errorf("Unexpected final progress: %v", last)
+		}
+	})
+}
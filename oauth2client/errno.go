@@ -0,0 +1,15 @@
+//go:build !plan9
+
+package oauth2client
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnectionError reports whether err is a reset, refused or broken connection.
+func isConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
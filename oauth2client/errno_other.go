@@ -0,0 +1,9 @@
+//go:build plan9
+
+package oauth2client
+
+// isConnectionError reports whether err is a reset, refused or broken
+// connection. Plan 9 has no errno values for these, so it reports false.
+func isConnectionError(err error) bool {
+	return false
+}
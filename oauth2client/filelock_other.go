@@ -0,0 +1,27 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package oauth2client
+
+import (
+	"os"
+	"sync"
+)
+
+// fileLocks holds a mutex per lock file path. Without flock or LockFileEx,
+// the lock only excludes other lockers in the same process.
+var fileLocks sync.Map
+
+// lockFile blocks until no other caller in this process holds the lock on f's path.
+func lockFile(f *os.File) error {
+	mutex, _ := fileLocks.LoadOrStore(f.Name(), &sync.Mutex{})
+	mutex.(*sync.Mutex).Lock()
+	return nil
+}
+
+// unlockFile releases the lock on f's path.
+func unlockFile(f *os.File) error {
+	if mutex, ok := fileLocks.Load(f.Name()); ok {
+		mutex.(*sync.Mutex).Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package oauth2client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP message signature algorithms, as registered by RFC 9421.
+const (
+	SignatureHMACSHA256        = "hmac-sha256"
+	SignatureEd25519           = "ed25519"
+	SignatureECDSAP256SHA256   = "ecdsa-p256-sha256"
+	SignatureECDSAP384SHA384   = "ecdsa-p384-sha384"
+	SignatureRSAPSSSHA512      = "rsa-pss-sha512"
+	SignatureRSAPKCS1v15SHA256 = "rsa-v1_5-sha256"
+)
+
+// HTTPMessageSigner signs requests with HTTP Message Signatures (RFC 9421),
+// adding the Signature-Input and Signature headers. Requests with a body also
+// get a Content-Digest header (RFC 9530).
+//
+// HTTPMessageSigner is an Authenticator. To cover the Authorization header of
+// another Authenticator, run the signer after it with ChainAuthenticators.
+//
+// Example:
+//
+//	signer := &oauth2client.HTTPMessageSigner{
+//		KeyID: "partner-key-1",
+//		Key:   ed25519PrivateKey,
+//	}
+//	auth := oauth2client.ChainAuthenticators(oauth2client.TokenSourceAuth{Source: config.TokenSource()}, signer)
+//	client := oauth2client.NewAPIClientWithAuthenticator(auth, "https://api.bank.example")
+type HTTPMessageSigner struct {
+	// KeyID identifies the key to the verifier and is sent as the keyid parameter.
+	KeyID string
+
+	// Key is the signing key: a []byte shared secret for HMAC, an
+	// ed25519.PrivateKey, an *ecdsa.PrivateKey or an *rsa.PrivateKey.
+	Key interface{}
+
+	// Algorithm is one of the Signature* constants. If empty, it is inferred
+	// from Key, using rsa-pss-sha512 for RSA keys.
+	Algorithm string
+
+	// Components lists the covered components, such as "@method",
+	// "@target-uri", "@authority", "@path", "@query" or a lowercase header
+	// name. Header components missing from a request are left out of its
+	// signature. If empty, "@method", "@target-uri", "content-digest" and
+	// "authorization" are covered.
+	Components []string
+
+	// Label names the signature in the Signature-Input and Signature headers.
+	// If empty, "sig1" is used.
+	Label string
+
+	// Tag is sent as the tag parameter if set.
+	Tag string
+
+	// Expires, if positive, sets the expires parameter to the creation time plus Expires.
+	Expires time.Duration
+
+	// DigestAlgorithm is "sha-256" (the default) or "sha-512" and selects the
+	// hash for the Content-Digest header.
+	DigestAlgorithm string
+
+	// now returns the signing time; it is replaced in tests.
+	now func() time.Time
+}
+
+var defaultSignatureComponents = []string{"@method", "@target-uri", "content-digest", "authorization"}
+
+// Authenticate adds Content-Digest, Signature-Input and Signature headers to req.
+func (s *HTTPMessageSigner) Authenticate(req *http.Request) error {
+	algorithm, err := s.algorithm()
+	if err != nil {
+		return err
+	}
+
+	if err := setContentDigest(req, s.DigestAlgorithm); err != nil {
+		return err
+	}
+
+	components := s.Components
+	if len(components) == 0 {
+		components = defaultSignatureComponents
+	}
+	covered := make([]string, 0, len(components))
+	for _, component := range components {
+		component = strings.ToLower(component)
+		if !strings.HasPrefix(component, "@") && len(req.Header.Values(component)) == 0 {
+			continue
+		}
+		covered = append(covered, component)
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	created := now().Unix()
+
+	params := signatureParams(covered, created, s.Expires, s.KeyID, algorithm, s.Tag)
+	base, err := signatureBase(req, covered, params)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.sign(algorithm, []byte(base))
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+	req.Header.Set("Signature-Input", label+"="+params)
+	req.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// algorithm returns the configured algorithm or infers it from the key type.
+func (s *HTTPMessageSigner) algorithm() (string, error) {
+	if s.Algorithm != "" {
+		return s.Algorithm, nil
+	}
+	switch key := s.Key.(type) {
+	case []byte:
+		return SignatureHMACSHA256, nil
+	case ed25519.PrivateKey:
+		return SignatureEd25519, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return SignatureECDSAP256SHA256, nil
+		case elliptic.P384():
+			return SignatureECDSAP384SHA384, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", key.Curve.Params().Name)
+	case *rsa.PrivateKey:
+		return SignatureRSAPSSSHA512, nil
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", s.Key)
+	}
+}
+
+func (s *HTTPMessageSigner) sign(algorithm string, base []byte) ([]byte, error) {
+	switch algorithm {
+	case SignatureHMACSHA256:
+		key, ok := s.Key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%s requires a []byte key, got %T", algorithm, s.Key)
+		}
+		return hmacSHA256(key, base), nil
+	case SignatureEd25519:
+		key, ok := s.Key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an ed25519.PrivateKey, got %T", algorithm, s.Key)
+		}
+		return ed25519.Sign(key, base), nil
+	case SignatureECDSAP256SHA256, SignatureECDSAP384SHA384:
+		key, ok := s.Key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an *ecdsa.PrivateKey, got %T", algorithm, s.Key)
+		}
+		h, size := sha256.New(), 32
+		if algorithm == SignatureECDSAP384SHA384 {
+			h, size = sha512.New384(), 48
+		}
+		h.Write(base)
+		r, sig, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		// RFC 9421 encodes ECDSA signatures as the fixed-size concatenation of r and s.
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		sig.FillBytes(signature[size:])
+		return signature, nil
+	case SignatureRSAPSSSHA512:
+		key, ok := s.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an *rsa.PrivateKey, got %T", algorithm, s.Key)
+		}
+		digest := sha512.Sum512(base)
+		return rsa.SignPSS(rand.Reader, key, crypto.SHA512, digest[:], &rsa.PSSOptions{SaltLength: 64})
+	case SignatureRSAPKCS1v15SHA256:
+		key, ok := s.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an *rsa.PrivateKey, got %T", algorithm, s.Key)
+		}
+		digest := sha256.Sum256(base)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+}
+
+// signatureParams serializes the covered components and signature parameters
+// as the value of the @signature-params component.
+func signatureParams(covered []string, created int64, expires time.Duration, keyID, algorithm, tag string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, component := range covered {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(component))
+	}
+	b.WriteByte(')')
+	b.WriteString(";created=" + strconv.FormatInt(created, 10))
+	if expires > 0 {
+		b.WriteString(";expires=" + strconv.FormatInt(created+int64(expires/time.Second), 10))
+	}
+	if keyID != "" {
+		b.WriteString(";keyid=" + strconv.Quote(keyID))
+	}
+	if algorithm != "" {
+		b.WriteString(";alg=" + strconv.Quote(algorithm))
+	}
+	if tag != "" {
+		b.WriteString(";tag=" + strconv.Quote(tag))
+	}
+	return b.String()
+}
+
+// signatureBase builds the signature base of RFC 9421, section 2.5.
+func signatureBase(req *http.Request, covered []string, params string) (string, error) {
+	var b strings.Builder
+	for _, component := range covered {
+		value, err := componentValue(req, component)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(strconv.Quote(component))
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(params)
+	return b.String(), nil
+}
+
+// componentValue returns the value of a derived component or header field.
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return strings.ToUpper(req.Method), nil
+	case "@target-uri":
+		u := *req.URL
+		u.Fragment = ""
+		u.RawFragment = ""
+		u.User = nil
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = requestAuthority(req)
+		return u.String(), nil
+	case "@authority":
+		return requestAuthority(req), nil
+	case "@scheme":
+		return strings.ToLower(req.URL.Scheme), nil
+	case "@request-target":
+		return req.URL.RequestURI(), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported signature component %q", component)
+	}
+
+	values := req.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("signature component %q is missing from the request", component)
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// requestAuthority returns the lowercase host of req without a default port.
+func requestAuthority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host = strings.ToLower(host)
+	switch {
+	case strings.EqualFold(req.URL.Scheme, "https") && strings.HasSuffix(host, ":443"):
+		host = strings.TrimSuffix(host, ":443")
+	case strings.EqualFold(req.URL.Scheme, "http") && strings.HasSuffix(host, ":80"):
+		host = strings.TrimSuffix(host, ":80")
+	}
+	return host
+}
+
+// setContentDigest sets the Content-Digest header of a request with a body.
+func setContentDigest(req *http.Request, algorithm string) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "", "sha-256":
+		algorithm, h = "sha-256", sha256.New()
+	case "sha-512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported content digest algorithm %q", algorithm)
+	}
+
+	if err := hashRequestBodyInto(req, h); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Digest", algorithm+"=:"+base64.StdEncoding.EncodeToString(h.Sum(nil))+":")
+	return nil
+}
@@ -0,0 +1,164 @@
+package oauth2client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// GetJSON sends a GET request for path and decodes the JSON response into a T.
+//
+// Example:
+//
+//	type User struct {
+//		ID   int    `json:"id"`
+//		Name string `json:"name"`
+//	}
+//	user, err := oauth2client.GetJSON[User](ctx, client, "/users/42")
+func GetJSON[T any](ctx context.Context, c *APIClient, path string, opts ...CallOption) (T, error) {
+	return DoJSON[any, T](ctx, c, HttpGet, path, nil, opts...)
+}
+
+// PostJSON sends body as JSON in a POST request for path and decodes the JSON
+// response into a Resp.
+//
+// Example:
+//
+//	created, err := oauth2client.PostJSON[NewUser, User](ctx, client, "/users", NewUser{Name: "John Doe"},
+//		oauth2client.WithStrictJSON(),
+//		oauth2client.WithErrorType[*ValidationError](),
+//	)
+func PostJSON[Req, Resp any](ctx context.Context, c *APIClient, path string, body Req, opts ...CallOption) (Resp, error) {
+	return DoJSON[Req, Resp](ctx, c, HttpPost, path, body, opts...)
+}
+
+// DoJSON sends body as JSON using method and decodes the JSON response into a
+// Resp. A nil body sends no request body. An empty response body, such as that
+// of 204 No Content, leaves the result at its zero value.
+//
+// Unsuccessful responses are returned as an *APIError. With WithErrorType, the
+// error body is also decoded into the given type, and the returned error
+// matches both with errors.As.
+func DoJSON[Req, Resp any](ctx context.Context, c *APIClient, method HttpMethod, path string, body Req, opts ...CallOption) (Resp, error) {
+	var result Resp
+	options := c.callOptions(opts)
+
+	var requestBody interface{}
+	headers := map[string]string{"Accept": "application/json"}
+	if !isNilValue(body) {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return result, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		requestBody = encoded
+		headers["Content-Type"] = "application/json"
+	}
+
+	response, err := c.CallAPIWithResponse(ctx, method, path, requestBody, headers, opts...)
+	if err != nil {
+		var apiErr *APIError
+		if options.decodeError != nil && errors.As(err, &apiErr) {
+			if decoded, ok := options.decodeError(apiErr.Body); ok {
+				return result, &decodedAPIError{APIError: apiErr, decoded: decoded}
+			}
+		}
+		return result, err
+	}
+
+	if len(bytes.TrimSpace(response.Body)) == 0 {
+		return result, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(response.Body))
+	if options.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if options.jsonNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	if options.strictJSON {
+		if _, err := decoder.Token(); err != io.EOF {
+			return result, errors.New("failed to decode response body: unexpected data after JSON value")
+		}
+	}
+	return result, nil
+}
+
+// WithStrictJSON makes the JSON helpers reject response fields that the
+// result type does not have, and data after the JSON value.
+func WithStrictJSON() CallOption {
+	return func(o *callOptions) {
+		o.strictJSON = true
+	}
+}
+
+// WithJSONNumber makes the JSON helpers decode numbers into interface{}
+// values as json.Number instead of float64, preserving large integers.
+func WithJSONNumber() CallOption {
+	return func(o *callOptions) {
+		o.jsonNumber = true
+	}
+}
+
+// WithErrorType makes the JSON helpers decode the body of an unsuccessful
+// response into an E. E is usually a pointer to a struct implementing error.
+//
+// Example:
+//
+//	type ValidationError struct {
+//		Fields map[string]string `json:"fields"`
+//	}
+//
+//	func (e *ValidationError) Error() string { return fmt.Sprintf("invalid fields: %v", e.Fields) }
+//
+//	_, err := oauth2client.PostJSON[NewUser, User](ctx, client, "/users", newUser,
+//		oauth2client.WithErrorType[*ValidationError]())
+//	var validationErr *ValidationError
+//	if errors.As(err, &validationErr) {
+//		log.Println(validationErr.Fields)
+//	}
+func WithErrorType[E error]() CallOption {
+	return func(o *callOptions) {
+		o.decodeError = func(body []byte) (error, bool) {
+			var target E
+			if err := json.Unmarshal(body, &target); err != nil {
+				return nil, false
+			}
+			return target, any(target) != nil
+		}
+	}
+}
+
+// decodedAPIError is an APIError whose body was decoded by WithErrorType.
+type decodedAPIError struct {
+	*APIError
+	decoded error
+}
+
+// Error returns the message of the decoded error.
+func (e *decodedAPIError) Error() string {
+	return e.decoded.Error()
+}
+
+// Unwrap returns both the decoded error and the APIError.
+func (e *decodedAPIError) Unwrap() []error {
+	return []error{e.decoded, e.APIError}
+}
+
+// isNilValue reports whether v is nil or a nil pointer, slice, map or interface.
+func isNilValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package oauth2client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type jsonTestUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type jsonTestError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *jsonTestError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func TestJSONHelpers(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Unexpected Accept header: %q", r.Header.Get("Accept"))
+		}
+		switch r.URL.Path {
+		case "/users/42":
+			w.Write([]byte(`{"id": 42, "name": "John Doe", "email": "john@example.com"}`))
+		case "/users":
+			if r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Unexpected Content-Type: %q", r.Header.Get("Content-Type"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			var user jsonTestUser
+			if err := json.Unmarshal(body, &user); err != nil || user.Name == "" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"code": "invalid", "message": "name is required"}`))
+				return
+			}
+			user.ID = 7
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(user)
+		case "/count":
+			w.Write([]byte(`{"total": 9007199254740993}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/no-body":
+			body, _ := io.ReadAll(r.Body)
+			if len(body) != 0 || r.Header.Get("Content-Type") != "" {
+				t.Errorf("Unexpected request body: %q (%s)", body, r.Header.Get("Content-Type"))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	client := NewAPIClient(nil, apiServer.URL)
+	ctx := context.Background()
+
+	t.Run("GetJSON", func(t *testing.T) {
+		user, err := GetJSON[jsonTestUser](ctx, client, "/users/42")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if user.ID != 42 || user.Name != "John Doe" {
+			t.Errorf("Unexpected user: %+v", user)
+		}
+
+		if _, err := GetJSON[jsonTestUser](ctx, client, "/users/42", WithStrictJSON()); err == nil {
+			t.Error("Expected error for unknown field with strict decoding")
+		}
+
+		if _, err := GetJSON[jsonTestUser](ctx, client, "/missing"); !IsNotFound(err) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("PostJSON", func(t *testing.T) {
+		created, err := PostJSON[jsonTestUser, jsonTestUser](ctx, client, "/users", jsonTestUser{Name: "Jane Doe"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if created.ID != 7 || created.Name != "Jane Doe" {
+			t.Errorf("Unexpected user: %+v", created)
+		}
+	})
+
+	t.Run("Error type", func(t *testing.T) {
+		_, err := PostJSON[jsonTestUser, jsonTestUser](ctx, client, "/users", jsonTestUser{}, WithErrorType[*jsonTestError]())
+		var typed *jsonTestError
+		if !errors.As(err, &typed) {
+			t.Fatalf("Expected *jsonTestError, got %T: %v", err, err)
+		}
+		if typed.Code != "invalid" || typed.Message != "name is required" {
+			t.Errorf("Unexpected decoded error: %+v", typed)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected *APIError with status 422, got %v", err)
+		}
+		if err.Error() != "invalid: name is required" {
+			t.Errorf("Unexpected error text: %s", err.Error())
+		}
+	})
+
+	t.Run("UseNumber", func(t *testing.T) {
+		result, err := GetJSON[map[string]interface{}](ctx, client, "/count", WithJSONNumber())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n, ok := result["total"].(json.Number); !ok || n.String() != "9007199254740993" {
+			t.Errorf("Unexpected total: %#v", result["total"])
+		}
+	})
+
+	t.Run("Nil body", func(t *testing.T) {
+		if _, err := DoJSON[*jsonTestUser, *jsonTestUser](ctx, client, HttpPost, "/no-body", nil); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := DoJSON[[]string, *jsonTestUser](ctx, client, HttpPut, "/no-body", nil); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Empty response", func(t *testing.T) {
+		user, err := GetJSON[*jsonTestUser](ctx, client, "/empty")
+		if err != nil || user != nil {
+			t.Errorf("Unexpected result: %v, %v", user, err)
+		}
+	})
+}
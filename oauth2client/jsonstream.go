@@ -0,0 +1,211 @@
+package oauth2client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RecordIterator decodes the records of a streamed response one at a time.
+//
+// Example:
+//
+//	records, err := oauth2client.StreamNDJSON[Order](ctx, client, oauth2client.HttpGet, "/exports/orders", nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer records.Close()
+//	for records.Next() {
+//		order := records.Record()
+//		...
+//	}
+//	if err := records.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+type RecordIterator[T any] struct {
+	body    io.ReadCloser
+	decode  func() (T, int64, error)
+	record  T
+	index   int
+	options callOptions
+	err     error
+	done    bool
+}
+
+// RecordError reports a record that could not be read or decoded.
+type RecordError struct {
+	// Index is the zero-based index of the record.
+	Index int
+
+	// Offset is the byte offset in the (decompressed) response body at which
+	// the record starts.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("failed to decode record %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Next decodes the next record and reports whether there is one. It returns
+// false at the end of the stream or on the first error, which Err returns.
+func (it *RecordIterator[T]) Next() bool {
+	if it.done {
+		return false
+	}
+	record, offset, err := it.decode()
+	if err != nil {
+		it.done = true
+		var zero T
+		it.record = zero
+		if err != io.EOF {
+			it.err = &RecordError{Index: it.index + 1, Offset: offset, Err: err}
+		}
+		return false
+	}
+	it.record = record
+	it.index++
+	return true
+}
+
+// Record returns the record decoded by the last call to Next.
+func (it *RecordIterator[T]) Record() T {
+	return it.record
+}
+
+// Index returns the zero-based index of the record decoded by the last call to Next.
+func (it *RecordIterator[T]) Index() int {
+	return it.index
+}
+
+// Err returns the error that stopped the iteration, or nil at the end of the stream.
+func (it *RecordIterator[T]) Err() error {
+	return it.err
+}
+
+// Close closes the response body. It must be called when the iteration is done.
+func (it *RecordIterator[T]) Close() error {
+	it.done = true
+	return it.body.Close()
+}
+
+// newDecoder returns a JSON decoder for r configured by the call options.
+func (it *RecordIterator[T]) newDecoder(r io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	if it.options.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if it.options.jsonNumber {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// StreamNDJSON calls the API and decodes the newline-delimited JSON response
+// one record at a time. Blank lines are skipped. WithStrictJSON and
+// WithJSONNumber apply to each record.
+func StreamNDJSON[T any](ctx context.Context, c *APIClient, method HttpMethod, path string, body interface{}, opts ...CallOption) (*RecordIterator[T], error) {
+	it, err := newRecordIterator[T](ctx, c, method, path, body, "application/x-ndjson", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(it.body)
+	var offset int64
+	it.decode = func() (T, int64, error) {
+		var record T
+		for {
+			start := offset
+			line, err := reader.ReadBytes('\n')
+			offset += int64(len(line))
+			if err != nil && err != io.EOF {
+				return record, start, err
+			}
+			if len(bytes.TrimSpace(line)) > 0 {
+				if err := it.newDecoder(bytes.NewReader(line)).Decode(&record); err != nil {
+					return record, start, err
+				}
+				return record, start, nil
+			}
+			if err != nil {
+				return record, start, err
+			}
+		}
+	}
+	return it, nil
+}
+
+// StreamJSONArray calls the API and decodes the elements of the top-level
+// JSON array in the response one at a time. WithStrictJSON and WithJSONNumber
+// apply to each element.
+func StreamJSONArray[T any](ctx context.Context, c *APIClient, method HttpMethod, path string, body interface{}, opts ...CallOption) (*RecordIterator[T], error) {
+	it, err := newRecordIterator[T](ctx, c, method, path, body, "application/json", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := it.newDecoder(it.body)
+	started := false
+	it.decode = func() (T, int64, error) {
+		var record T
+		if !started {
+			started = true
+			token, err := decoder.Token()
+			if err != nil {
+				return record, decoder.InputOffset(), err
+			}
+			if token != json.Delim('[') {
+				return record, decoder.InputOffset(), errors.New("response is not a JSON array")
+			}
+		}
+		if !decoder.More() {
+			offset := decoder.InputOffset()
+			if _, err := decoder.Token(); err != nil {
+				return record, offset, err
+			}
+			return record, offset, io.EOF
+		}
+		offset := decoder.InputOffset() + separatorLength(decoder.Buffered())
+		err := decoder.Decode(&record)
+		return record, offset, err
+	}
+	return it, nil
+}
+
+// separatorLength returns the number of whitespace and comma bytes at the
+// start of r, which the decoder skips before the next array element.
+func separatorLength(r io.Reader) int64 {
+	var n int64
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil {
+			return n
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n', ',':
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// newRecordIterator sends the request and returns an iterator over its body,
+// without a decode function.
+func newRecordIterator[T any](ctx context.Context, c *APIClient, method HttpMethod, path string, body interface{}, accept string, opts []CallOption) (*RecordIterator[T], error) {
+	stream, _, err := c.CallAPIStream(ctx, method, path, body, map[string]string{"Accept": accept}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordIterator[T]{body: stream, index: -1, options: c.callOptions(opts)}, nil
+}
@@ -0,0 +1,100 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordIterator(t *testing.T) {
+	type record struct {
+		ID int `json:"id"`
+	}
+
+	responses := map[string]string{
+		"/ndjson":      "{\"id\":1}\n\n{\"id\":2}\r\n{\"id\":3}",
+		"/ndjson-bad":  "{\"id\":1}\n{\"id\":\"two\"}\n{\"id\":3}\n",
+		"/array":       ` [ {"id":1}, {"id":2} ,{"id":3}] `,
+		"/array-bad":   `[{"id":1},{"id":2},{"id":"three"}]`,
+		"/array-extra": `[{"id":1,"name":"one"}]`,
+		"/object":      `{"id":1}`,
+	}
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[r.URL.Path]))
+	}))
+	defer apiServer.Close()
+	client := NewAPIClient(nil, apiServer.URL)
+	ctx := context.Background()
+
+	collect := func(it *RecordIterator[record]) []int {
+		defer it.Close()
+		var ids []int
+		for it.Next() {
+			if it.Index() != len(ids) {
+				t.Errorf("Unexpected index %d for record %d", it.Index(), len(ids))
+			}
+			ids = append(ids, it.Record().ID)
+		}
+		return ids
+	}
+
+	t.Run("NDJSON", func(t *testing.T) {
+		it, err := StreamNDJSON[record](ctx, client, HttpGet, "/ndjson", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ids := collect(it); len(ids) != 3 || ids[2] != 3 || it.Err() != nil {
+			t.Errorf("Unexpected records: %v, %v", ids, it.Err())
+		}
+	})
+
+	t.Run("NDJSON decode error", func(t *testing.T) {
+		it, _ := StreamNDJSON[record](ctx, client, HttpGet, "/ndjson-bad", nil)
+		ids := collect(it)
+		var recordErr *RecordError
+		if len(ids) != 1 || !errors.As(it.Err(), &recordErr) {
+			t.Fatalf("Unexpected result: %v, %v", ids, it.Err())
+		}
+		if recordErr.Index != 1 || recordErr.Offset != 9 {
+			t.Errorf("Unexpected error position: %+v", recordErr)
+		}
+	})
+
+	t.Run("JSON array", func(t *testing.T) {
+		it, err := StreamJSONArray[record](ctx, client, HttpGet, "/array", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ids := collect(it); len(ids) != 3 || ids[0] != 1 || ids[2] != 3 || it.Err() != nil {
+			t.Errorf("Unexpected records: %v, %v", ids, it.Err())
+		}
+	})
+
+	t.Run("JSON array decode error", func(t *testing.T) {
+		it, _ := StreamJSONArray[record](ctx, client, HttpGet, "/array-bad", nil)
+		ids := collect(it)
+		var recordErr *RecordError
+		if len(ids) != 2 || !errors.As(it.Err(), &recordErr) {
+			t.Fatalf("Unexpected result: %v, %v", ids, it.Err())
+		}
+		if recordErr.Index != 2 || recordErr.Offset != 19 {
+			t.Errorf("Unexpected error position: %+v", recordErr)
+		}
+	})
+
+	t.Run("Strict decoding", func(t *testing.T) {
+		it, _ := StreamJSONArray[record](ctx, client, HttpGet, "/array-extra", nil, WithStrictJSON())
+		if ids := collect(it); len(ids) != 0 || it.Err() == nil {
+			t.Errorf("Expected unknown field error, got %v, %v", ids, it.Err())
+		}
+	})
+
+	t.Run("Not an array", func(t *testing.T) {
+		it, _ := StreamJSONArray[record](ctx, client, HttpGet, "/object", nil)
+		if ids := collect(it); len(ids) != 0 || it.Err() == nil {
+			t.Errorf("Expected error for non-array response, got %v, %v", ids, it.Err())
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package oauth2client
+
+import "net/http"
+
+// RoundTripFunc sends a request and returns its response.
+// It implements http.RoundTripper.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the sending of a request. It may inspect or modify the
+// request before calling next, and the response or error after it returns.
+//
+// Middleware runs for every request sent by an APIClient, including retries,
+// after the request has been authenticated. Modifying a signed request may
+// invalidate its signature.
+//
+// Example:
+//
+//	logging := func(next oauth2client.RoundTripFunc) oauth2client.RoundTripFunc {
+//		return func(req *http.Request) (*http.Response, error) {
+//			start := time.Now()
+//			resp, err := next(req)
+//			log.Printf("%s %s took %v", req.Method, req.URL, time.Since(start))
+//			return resp, err
+//		}
+//	}
+//	client, err := oauth2client.NewAPIClientWithOptions(baseURL, oauth2client.WithMiddleware(logging))
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// chainMiddleware wraps send with middleware so that the first middleware
+// sees the request first and the response last.
+func chainMiddleware(middleware []Middleware, send RoundTripFunc) RoundTripFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		send = middleware[i](send)
+	}
+	return send
+}
@@ -0,0 +1,94 @@
+package oauth2client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Request-Id")))
+	}))
+	defer apiServer.Close()
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" request")
+				resp, err := next(req)
+				calls = append(calls, name+" response")
+				return resp, err
+			}
+		}
+	}
+	requestID := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Request-Id", "req-123")
+			return next(req)
+		}
+	}
+
+	client, err := NewAPIClientWithOptions(apiServer.URL,
+		WithAuthenticator(BearerTokenAuth{Token: "static_token"}),
+		WithMiddleware(record("outer"), record("inner")),
+		WithMiddleware(requestID),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Order and request modification", func(t *testing.T) {
+		calls = nil
+		response, _, err := client.CallAPI(HttpGet, "/", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(response) != "req-123" {
+			t.Errorf("Unexpected response: %s", response)
+		}
+		want := "outer request,inner request,inner response,outer response"
+		if got := strings.Join(calls, ","); got != want {
+			t.Errorf("Unexpected middleware order: %s", got)
+		}
+	})
+
+	t.Run("DownloadFile", func(t *testing.T) {
+		calls = nil
+		destPath := filepath.Join(t.TempDir(), "download.txt")
+		if err := client.DownloadFile(HttpGet, "/", nil, nil, destPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content, _ := os.ReadFile(destPath)
+		if string(content) != "req-123" || len(calls) != 4 {
+			t.Errorf("Unexpected download: %q, calls %v", content, calls)
+		}
+	})
+
+	t.Run("Response and error handling", func(t *testing.T) {
+		errUpstream := errors.New("upstream unavailable")
+		failing, _ := NewAPIClientWithOptions(apiServer.URL, WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				resp, err := next(req)
+				if err == nil && resp.StatusCode >= 500 {
+					resp.Body.Close()
+					return nil, errUpstream
+				}
+				return resp, err
+			}
+		}))
+		_, _, err := failing.CallAPI(HttpGet, "/fail", nil, nil)
+		if !errors.Is(err, errUpstream) {
+			t.Errorf("Expected middleware error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package oauth2client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+// MultipartForm describes a multipart/form-data request body. Fields are
+// written first, then files, each in the given order.
+//
+// Example:
+//
+//	form := &oauth2client.MultipartForm{
+//		Fields: []oauth2client.MultipartField{{Name: "description", Value: "Q3 report"}},
+//		Files:  []oauth2client.MultipartFile{{FieldName: "file", Path: "./report.pdf"}},
+//	}
+//	response, statusCode, err := client.UploadMultipart(ctx, oauth2client.HttpPost, "/documents", form, nil)
+type MultipartForm struct {
+	Fields []MultipartField
+	Files  []MultipartFile
+}
+
+// MultipartField is a form field with a text value.
+type MultipartField struct {
+	Name  string
+	Value string
+}
+
+// MultipartFile is a form field with file content. Exactly one of Path, Open
+// and Reader supplies the content.
+type MultipartFile struct {
+	// FieldName is the name of the form field.
+	FieldName string
+
+	// FileName is sent as the filename of the part. Defaults to the base name of Path.
+	FileName string
+
+	// ContentType is the content type of the part. Defaults to the type
+	// registered for the file name extension, or "application/octet-stream".
+	ContentType string
+
+	// Path is a file that is opened again for every request sent.
+	Path string
+
+	// Open returns the content and is called again for every request sent.
+	Open func() (io.ReadCloser, error)
+
+	// Reader supplies the content once. A form with a Reader part is not sent
+	// again after a token refresh or a retryable failure.
+	Reader io.Reader
+}
+
+// RequestBody returns a streamed body for the form. The parts are written
+// through an io.Pipe as the request is sent, so files are never held in
+// memory. The body is replayable unless a file is supplied by a Reader.
+func (f *MultipartForm) RequestBody() (*RequestBody, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	replayable := true
+	for _, file := range f.Files {
+		sources := 0
+		for _, set := range []bool{file.Path != "", file.Open != nil, file.Reader != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("multipart file %q must have exactly one of Path, Open and Reader", file.FieldName)
+		}
+		if file.Reader != nil {
+			replayable = false
+		}
+	}
+
+	body := &RequestBody{
+		ContentType:   "multipart/form-data; boundary=" + boundary,
+		ContentLength: -1,
+	}
+	getBody := func() (io.ReadCloser, error) {
+		return f.open(boundary), nil
+	}
+	if replayable {
+		body.GetBody = getBody
+	} else {
+		body.Reader = f.open(boundary)
+	}
+	return body, nil
+}
+
+// open starts writing the form to a pipe and returns its reading end.
+func (f *MultipartForm) open(boundary string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f.write(pw, boundary))
+	}()
+	return pr
+}
+
+// write writes the whole form to w.
+func (f *MultipartForm) write(w io.Writer, boundary string) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, field := range f.Fields {
+		if err := mw.WriteField(field.Name, field.Value); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range f.Files {
+		if err := file.write(mw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// write adds the file as a part of mw.
+func (file *MultipartFile) write(mw *multipart.Writer) error {
+	var content io.Reader
+	switch {
+	case file.Path != "":
+		f, err := os.Open(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open multipart file: %w", err)
+		}
+		defer f.Close()
+		content = f
+	case file.Open != nil:
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open multipart file: %w", err)
+		}
+		defer rc.Close()
+		content = rc
+	default:
+		content = file.Reader
+	}
+
+	fileName := file.FileName
+	if fileName == "" && file.Path != "" {
+		fileName = filepath.Base(file.Path)
+	}
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileName))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name":     file.FieldName,
+		"filename": fileName,
+	}))
+	header.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return fmt.Errorf("failed to write multipart file: %w", err)
+	}
+	return nil
+}
+
+// randomBoundary returns a multipart boundary. It is chosen once per form so
+// that replayed bodies are identical.
+func randomBoundary() (string, error) {
+	var buf [24]byte
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		return "", fmt.Errorf("failed to generate multipart boundary: %w", err)
+	}
+	return fmt.Sprintf("%x", buf[:]), nil
+}
+
+// UploadMultipart sends form as a streamed multipart/form-data request body.
+//
+// Parameters:
+//   - ctx: A context.Context for controlling cancellation and timeouts
+//   - method: The HTTP method to use (typically HttpPost or HttpPut)
+//   - path: The API endpoint path (will be appended to the base URL)
+//   - form: The fields and files to upload
+//   - additionalHeaders: Additional HTTP headers to include in the request
+//   - opts: Per-call options, such as WithCallStatusPolicy
+//
+// Returns:
+//   - []byte: The response body
+//   - int: The HTTP status code
+//   - error: Any error that occurred during the upload
+//
+// Files given by Path or Open are read again if the request is re-sent after a
+// token refresh or retried, so uploads survive both without buffering.
+//
+// Example:
+//
+//	form := &oauth2client.MultipartForm{
+//		Fields: []oauth2client.MultipartField{{Name: "description", Value: "Q3 report"}},
+//		Files: []oauth2client.MultipartFile{
+//			{FieldName: "file", Path: "./report.pdf"},
+//			{FieldName: "thumbnail", FileName: "thumb.png", Reader: thumbnail},
+//		},
+//	}
+//	response, statusCode, err := client.UploadMultipart(ctx, oauth2client.HttpPost, "/documents", form, nil)
+func (c *APIClient) UploadMultipart(ctx context.Context, method HttpMethod, path string, form *MultipartForm, additionalHeaders map[string]string, opts ...CallOption) ([]byte, int, error) {
+	if form == nil {
+		return nil, 0, errors.New("multipart form must not be nil")
+	}
+	body, err := form.RequestBody()
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.callAPI(ctx, method, path, body, additionalHeaders, opts)
+}
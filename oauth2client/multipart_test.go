@@ -0,0 +1,120 @@
+package oauth2client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUploadMultipart(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token_" + string(rune('0'+n)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	var apiRequests int32
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&apiRequests, 1)
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			content, _ := io.ReadAll(part)
+			parts = append(parts, fmt.Sprintf("%s|%s|%s|%d", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), len(content)))
+		}
+		switch {
+		case r.Header.Get("Authorization") == "Bearer token_1":
+			w.WriteHeader(http.StatusUnauthorized)
+		case n == 2 && r.URL.Path == "/flaky":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(strings.Join(parts, ",")))
+		}
+	}))
+	defer apiServer.Close()
+
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "report.pdf")
+	os.WriteFile(pdfPath, make([]byte, 1<<20), 0600)
+
+	newForm := func() *MultipartForm {
+		return &MultipartForm{
+			Fields: []MultipartField{{Name: "description", Value: "Q3 report"}},
+			Files: []MultipartFile{
+				{FieldName: "file", Path: pdfPath},
+				{FieldName: "notes", FileName: "notes.txt", ContentType: "text/plain; charset=utf-8", Open: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("hello")), nil
+				}},
+			},
+		}
+	}
+	want := "description|||9,file|report.pdf|application/pdf|1048576,notes|notes.txt|text/plain; charset=utf-8|5"
+
+	newClient := func() *APIClient {
+		atomic.StoreInt32(&tokenRequests, 0)
+		atomic.StoreInt32(&apiRequests, 0)
+		client, _ := NewAPIClientWithOptions(apiServer.URL,
+			WithOAuth2Config(OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, RetryNonIdempotent: true}),
+		)
+		return client
+	}
+
+	t.Run("Replayed after token refresh and retry", func(t *testing.T) {
+		response, statusCode, err := newClient().UploadMultipart(context.Background(), HttpPost, "/flaky", newForm(), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if statusCode != http.StatusOK || string(response) != want {
+			t.Errorf("Unexpected response: %d\n got %s\nwant %s", statusCode, response, want)
+		}
+		if n := atomic.LoadInt32(&apiRequests); n != 3 {
+			t.Errorf("Expected 3 requests, got %d", n)
+		}
+	})
+
+	t.Run("Reader part is sent once", func(t *testing.T) {
+		form := &MultipartForm{Files: []MultipartFile{{FieldName: "file", FileName: "data.bin", Reader: strings.NewReader("once")}}}
+		_, statusCode, err := newClient().UploadMultipart(context.Background(), HttpPost, "/upload", form, nil)
+		if err == nil || statusCode != http.StatusUnauthorized {
+			t.Errorf("Expected the 401 response, got %d, %v", statusCode, err)
+		}
+		if n := atomic.LoadInt32(&apiRequests); n != 1 {
+			t.Errorf("Expected 1 request, got %d", n)
+		}
+	})
+
+	t.Run("Invalid file source", func(t *testing.T) {
+		form := &MultipartForm{Files: []MultipartFile{{FieldName: "file"}}}
+		if _, _, err := newClient().UploadMultipart(context.Background(), HttpPost, "/upload", form, nil); err == nil {
+			t.Error("Expected error for file without content")
+		}
+	})
+}
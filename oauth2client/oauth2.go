@@ -14,23 +14,114 @@ import (
 
 // tokenManager handles OAuth2 token acquisition and refresh.
 type tokenManager struct {
-	config      OAuth2Config
-	accessToken string
-	expiresAt   time.Time
-	mutex       sync.Mutex
+	config       OAuth2Config
+	accessToken  string
+	tokenType    string
+	refreshValue string
+	extra        map[string]interface{}
+	expiresAt    time.Time
+	mutex        sync.Mutex
+
+	// httpClient sends token requests. If nil, a default client is used.
+	httpClient *http.Client
+
+	// breaker guards the token endpoint. If nil, token requests are always sent.
+	breaker *circuitBreaker
+}
+
+// Token implements TokenSource. It returns the cached token, refreshing it
+// first if it is missing or expired.
+func (tm *tokenManager) Token() (*Token, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tm.accessToken == "" || time.Now().After(tm.expiresAt) {
+		if err := tm.renewToken(""); err != nil {
+			return nil, err
+		}
+	}
+	return tm.token(), nil
 }
 
 // getValidToken returns a valid access token, refreshing if necessary.
 func (tm *tokenManager) getValidToken() (string, error) {
+	token, err := tm.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// refreshStaleToken replaces stale, an access token rejected by the server,
+// unless another caller has already replaced it.
+func (tm *tokenManager) refreshStaleToken(stale string) error {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
 
-	if tm.accessToken == "" || time.Now().After(tm.expiresAt) {
-		if err := tm.refreshToken(); err != nil {
-			return "", err
+	if tm.accessToken != stale {
+		return nil
+	}
+	return tm.renewToken(stale)
+}
+
+// renewToken adopts a valid token from the TokenStore, ignoring stale, or
+// requests a new one. If the store implements TokenStoreLocker it stays locked
+// until the new token is saved, so only one process requests a token at a time.
+func (tm *tokenManager) renewToken(stale string) (err error) {
+	if locker, ok := tm.config.TokenStore.(TokenStoreLocker); ok {
+		unlock, err := locker.Lock(tokenStoreKey(tm.config))
+		if err != nil {
+			return err
 		}
+		defer func() {
+			if unlockErr := unlock(); unlockErr != nil && err == nil {
+				err = fmt.Errorf("failed to unlock token store: %w", unlockErr)
+			}
+		}()
+	}
+
+	loaded, err := tm.loadStoredToken(stale)
+	if err != nil || loaded {
+		return err
+	}
+	return tm.refreshToken()
+}
+
+// loadStoredToken adopts the token from the configured TokenStore if it is
+// still valid and differs from stale.
+func (tm *tokenManager) loadStoredToken(stale string) (bool, error) {
+	if tm.config.TokenStore == nil {
+		return false, nil
+	}
+	token, err := tm.config.TokenStore.Load(tokenStoreKey(tm.config))
+	if err != nil {
+		return false, fmt.Errorf("failed to load stored token: %w", err)
+	}
+	if token == nil || token.AccessToken == "" || token.AccessToken == stale || time.Now().After(token.Expiry) {
+		return false, nil
+	}
+	tm.setToken(token)
+	return true, nil
+}
+
+// setToken replaces the cached token.
+func (tm *tokenManager) setToken(token *Token) {
+	tm.accessToken = token.AccessToken
+	tm.tokenType = token.TokenType
+	tm.refreshValue = token.RefreshToken
+	tm.extra = token.Extra
+	tm.expiresAt = token.Expiry
+}
+
+// token returns the cached token.
+func (tm *tokenManager) token() *Token {
+	return &Token{
+		AccessToken:  tm.accessToken,
+		TokenType:    tm.tokenType,
+		RefreshToken: tm.refreshValue,
+		Expiry:       tm.expiresAt,
+		Extra:        tm.extra,
 	}
-	return tm.accessToken, nil
 }
 
 // refreshToken requests a new access token from the authorization server.
@@ -48,8 +139,18 @@ func (tm *tokenManager) refreshToken() error {
 	req.Header.Set("Authorization", "Basic "+auth)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
+	client := tm.httpClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	done := func(*http.Response, error) {}
+	if tm.breaker != nil {
+		if done, err = tm.breaker.allow(); err != nil {
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+	}
 	resp, err := client.Do(req)
+	done(resp, err)
 	if err != nil {
 		return err
 	}
@@ -60,12 +161,36 @@ func (tm *tokenManager) refreshToken() error {
 		return fmt.Errorf("failed to get token: %s", string(body))
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
 	var tokenResp tokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
 		return err
 	}
 
-	tm.accessToken = tokenResp.AccessToken
-	tm.expiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn-60) * time.Second)
+	var extra map[string]interface{}
+	if err := json.Unmarshal(body, &extra); err != nil {
+		return err
+	}
+	for _, field := range []string{"access_token", "token_type", "expires_in", "refresh_token"} {
+		delete(extra, field)
+	}
+
+	tm.setToken(&Token{
+		AccessToken:  tokenResp.AccessToken,
+		TokenType:    tokenResp.TokenType,
+		RefreshToken: tokenResp.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(tokenResp.ExpiresIn-60) * time.Second),
+		Extra:        extra,
+	})
+
+	if tm.config.TokenStore != nil {
+		if err := tm.config.TokenStore.Save(tokenStoreKey(tm.config), tm.token()); err != nil {
+			return fmt.Errorf("failed to save token: %w", err)
+		}
+	}
 	return nil
 }
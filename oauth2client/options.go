@@ -0,0 +1,425 @@
+package oauth2client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ClientOption configures an APIClient created with NewAPIClientWithOptions.
+type ClientOption func(*clientOptions) error
+
+// clientOptions collects the settings of all ClientOptions before the client is built.
+type clientOptions struct {
+	authenticator Authenticator
+	tokenManager  *tokenManager
+
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+
+	tlsConfig    *tls.Config
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	proxy        func(*http.Request) (*url.URL, error)
+
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+
+	defaultHeaders http.Header
+	middleware     []Middleware
+	retryPolicy    *RetryPolicy
+
+	rateLimiter       *RateLimiter
+	pathRateLimiters  []pathRateLimiter
+	adaptiveRateLimit bool
+
+	circuitBreakers     *circuitBreakers
+	tokenCircuitBreaker *CircuitBreakerSettings
+
+	statusPolicy StatusPolicy
+}
+
+// NewAPIClientWithOptions creates a new APIClient for baseURL configured by opts.
+//
+// Parameters:
+//   - baseURL: The base URL of the API you're accessing.
+//   - opts: Options for authentication, the underlying HTTP client, TLS, proxying,
+//     connection pooling and default headers.
+//
+// Returns:
+//   - *APIClient: A new instance of APIClient.
+//   - error: Any error in the options, such as an unreadable CA file or an invalid proxy URL.
+//
+// Without options the client behaves like NewAPIClient(nil, baseURL).
+//
+// Example:
+//
+//	client, err := oauth2client.NewAPIClientWithOptions("https://api.example.com",
+//		oauth2client.WithOAuth2Config(config),
+//		oauth2client.WithTimeout(30*time.Second),
+//		oauth2client.WithRootCAFile("/etc/ssl/internal-ca.pem"),
+//		oauth2client.WithUserAgent("myapp/1.0"),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func NewAPIClientWithOptions(baseURL string, opts ...ClientOption) (*APIClient, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient, err := o.buildHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.tokenManager != nil {
+		o.tokenManager.httpClient = httpClient
+	}
+	if o.tokenCircuitBreaker != nil {
+		if o.tokenManager == nil {
+			return nil, errors.New("token circuit breaker requires an OAuth2 configuration")
+		}
+		o.tokenManager.breaker = newCircuitBreaker(tokenEndpointHost(o.tokenManager.config.TokenURL), *o.tokenCircuitBreaker)
+	}
+
+	return &APIClient{
+		tokenManager:   o.tokenManager,
+		authenticator:  o.authenticator,
+		baseURL:        baseURL,
+		httpClient:     httpClient,
+		defaultHeaders: o.defaultHeaders,
+		middleware:     o.middleware,
+		retryPolicy:    o.retryPolicy,
+
+		rateLimiter:      o.rateLimiter,
+		pathRateLimiters: o.pathRateLimiters,
+		quota:            quotaTracker{adaptive: o.adaptiveRateLimit},
+
+		circuitBreakers: o.circuitBreakers,
+
+		statusPolicy: o.statusPolicy,
+	}, nil
+}
+
+// buildHTTPClient returns the http.Client described by the options.
+func (o *clientOptions) buildHTTPClient() (*http.Client, error) {
+	client := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		client = &copied
+	}
+	if o.timeout > 0 {
+		client.Timeout = o.timeout
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+
+	if !o.configuresTransport() {
+		return client, nil
+	}
+
+	var transport *http.Transport
+	switch base := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = base.Clone()
+	default:
+		return nil, fmt.Errorf("TLS, proxy and connection pool options require an *http.Transport, got %T", base)
+	}
+
+	if o.tlsConfig != nil {
+		transport.TLSClientConfig = o.tlsConfig.Clone()
+	}
+	if o.rootCAs != nil || len(o.certificates) > 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		if o.rootCAs != nil {
+			transport.TLSClientConfig.RootCAs = o.rootCAs
+		}
+		transport.TLSClientConfig.Certificates = append(transport.TLSClientConfig.Certificates, o.certificates...)
+	}
+	if o.proxy != nil {
+		transport.Proxy = o.proxy
+	}
+	if o.maxIdleConns > 0 {
+		transport.MaxIdleConns = o.maxIdleConns
+	}
+	if o.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+	}
+	if o.maxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = o.maxConnsPerHost
+	}
+	if o.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = o.idleConnTimeout
+	}
+
+	client.Transport = transport
+	return client, nil
+}
+
+func (o *clientOptions) configuresTransport() bool {
+	return o.tlsConfig != nil || o.rootCAs != nil || len(o.certificates) > 0 || o.proxy != nil ||
+		o.maxIdleConns > 0 || o.maxIdleConnsPerHost > 0 || o.maxConnsPerHost > 0 || o.idleConnTimeout > 0
+}
+
+// WithOAuth2Config authenticates requests with tokens obtained from the token
+// endpoint using the client credentials grant, as NewAPIClient does. Token
+// requests use the same HTTP client settings as API requests.
+func WithOAuth2Config(config OAuth2Config) ClientOption {
+	return func(o *clientOptions) error {
+		o.tokenManager = &tokenManager{config: config}
+		o.authenticator = TokenSourceAuth{Source: o.tokenManager}
+		return nil
+	}
+}
+
+// WithTokenSource authenticates requests with tokens from source.
+func WithTokenSource(source TokenSource) ClientOption {
+	return func(o *clientOptions) error {
+		if source == nil {
+			return errors.New("token source must not be nil")
+		}
+		o.tokenManager, _ = source.(*tokenManager)
+		o.authenticator = TokenSourceAuth{Source: source}
+		return nil
+	}
+}
+
+// WithAuthenticator authenticates requests with auth.
+func WithAuthenticator(auth Authenticator) ClientOption {
+	return func(o *clientOptions) error {
+		o.tokenManager = nil
+		o.authenticator = auth
+		return nil
+	}
+}
+
+// WithHTTPClient uses a copy of client to send requests. Other options that
+// change the client or its transport are applied to the copy.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) error {
+		if client == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithTransport sends requests through transport.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(o *clientOptions) error {
+		if transport == nil {
+			return errors.New("transport must not be nil")
+		}
+		o.transport = transport
+		return nil
+	}
+}
+
+// WithTimeout limits the time a single request may take, including reading the response body.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithTLSConfig uses a copy of config for TLS connections.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(o *clientOptions) error {
+		o.tlsConfig = config
+		return nil
+	}
+}
+
+// WithRootCAs verifies server certificates against pool instead of the system roots.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(o *clientOptions) error {
+		o.rootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verifies server certificates against the PEM-encoded
+// certificates in path instead of the system roots.
+func WithRootCAFile(path string) ClientOption {
+	return func(o *clientOptions) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %s", path)
+		}
+		o.rootCAs = pool
+		return nil
+	}
+}
+
+// WithClientCertificate presents cert to servers that request client authentication.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(o *clientOptions) error {
+		o.certificates = append(o.certificates, cert)
+		return nil
+	}
+}
+
+// WithClientCertificateFile loads a PEM-encoded client certificate and key
+// and presents them to servers that request client authentication.
+func WithClientCertificateFile(certFile, keyFile string) ClientOption {
+	return func(o *clientOptions) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		o.certificates = append(o.certificates, cert)
+		return nil
+	}
+}
+
+// WithProxyURL sends all requests through the proxy at proxyURL instead of
+// the proxy from the environment.
+func WithProxyURL(proxyURL string) ClientOption {
+	return func(o *clientOptions) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", proxyURL)
+		}
+		o.proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithMaxIdleConns limits the number of idle connections across all hosts.
+func WithMaxIdleConns(n int) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxIdleConns = n
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost limits the number of idle connections kept per host.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// WithMaxConnsPerHost limits the number of connections per host, including
+// those in use.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxConnsPerHost = n
+		return nil
+	}
+}
+
+// WithIdleConnTimeout closes idle connections after timeout.
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.idleConnTimeout = timeout
+		return nil
+	}
+}
+
+// WithDefaultHeaders adds headers to every request. Headers passed to a call
+// take precedence over default headers.
+func WithDefaultHeaders(headers map[string]string) ClientOption {
+	return func(o *clientOptions) error {
+		if o.defaultHeaders == nil {
+			o.defaultHeaders = make(http.Header)
+		}
+		for key, value := range headers {
+			o.defaultHeaders.Set(key, value)
+		}
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return WithDefaultHeaders(map[string]string{"User-Agent": userAgent})
+}
+
+// WithMiddleware adds middleware around every request the client sends.
+// Middleware runs in the order it is added: the first sees the request first
+// and the response last.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(o *clientOptions) error {
+		o.middleware = append(o.middleware, middleware...)
+		return nil
+	}
+}
+
+// CallOption configures a single API call, overriding the client's settings.
+type CallOption func(*callOptions)
+
+// callOptions collects the settings of all CallOptions of one call.
+type callOptions struct {
+	statusPolicy StatusPolicy
+	headers      http.Header
+
+	strictJSON  bool
+	jsonNumber  bool
+	decodeError func(body []byte) (error, bool)
+
+	lastEventID string
+
+	progress         ProgressFunc
+	progressInterval time.Duration
+	expectedDigests  []expectedDigest
+}
+
+// callOptions applies opts on top of the client's settings.
+func (c *APIClient) callOptions(opts []CallOption) callOptions {
+	o := callOptions{statusPolicy: c.statusPolicy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.statusPolicy == nil {
+		o.statusPolicy = DefaultStatusPolicy
+	}
+	return o
+}
+
+// WithCallHeaders adds headers to the request of one call. They are applied
+// after the client's default headers and the call's additionalHeaders.
+func WithCallHeaders(headers map[string]string) CallOption {
+	return func(o *callOptions) {
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+		for key, value := range headers {
+			o.headers.Set(key, value)
+		}
+	}
+}
+
+// applyHeaders sets the call's headers on req.
+func (o *callOptions) applyHeaders(req *http.Request) {
+	for key, values := range o.headers {
+		req.Header[key] = values
+	}
+}
@@ -0,0 +1,97 @@
+package oauth2client
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAPIClientWithOptions(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "tls_access_token",
+				"expires_in":   3600,
+			})
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("User-Agent") + "|" + r.Header.Get("X-Tenant")))
+	}))
+	defer tlsServer.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+
+	t.Run("Root CAs, OAuth2 and default headers", func(t *testing.T) {
+		client, err := NewAPIClientWithOptions(tlsServer.URL,
+			WithOAuth2Config(OAuth2Config{TokenURL: tlsServer.URL + "/token", ClientID: "id", ClientSecret: "secret"}),
+			WithRootCAs(pool),
+			WithTimeout(5*time.Second),
+			WithMaxIdleConnsPerHost(4),
+			WithUserAgent("test-agent/1.0"),
+			WithDefaultHeaders(map[string]string{"X-Tenant": "default"}),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		response, _, err := client.CallAPI(HttpGet, "/", nil, map[string]string{"X-Tenant": "override"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(response) != "Bearer tls_access_token|test-agent/1.0|override" {
+			t.Errorf("Unexpected response: %s", response)
+		}
+		if client.httpClient.Timeout != 5*time.Second {
+			t.Errorf("Unexpected timeout: %v", client.httpClient.Timeout)
+		}
+	})
+
+	t.Run("Unknown CA", func(t *testing.T) {
+		client, err := NewAPIClientWithOptions(tlsServer.URL, WithRootCAs(x509.NewCertPool()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err == nil {
+			t.Error("Expected certificate verification error")
+		}
+	})
+
+	t.Run("Custom transport", func(t *testing.T) {
+		transport := RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     make(http.Header),
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		})
+		client, err := NewAPIClientWithOptions("https://api.example.com", WithTransport(transport))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, statusCode, err := client.CallAPI(HttpGet, "/", nil, nil); err != nil || statusCode != http.StatusOK {
+			t.Errorf("Unexpected result: %d, %v", statusCode, err)
+		}
+
+		if _, err := NewAPIClientWithOptions("https://api.example.com", WithTransport(transport), WithProxyURL("http://proxy:3128")); err == nil {
+			t.Error("Expected error for proxy option with a custom transport")
+		}
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		if _, err := NewAPIClientWithOptions("https://api.example.com", WithProxyURL("not a url")); err == nil {
+			t.Error("Expected error for invalid proxy URL")
+		}
+		if _, err := NewAPIClientWithOptions("https://api.example.com", WithRootCAFile("/nonexistent/ca.pem")); err == nil {
+			t.Error("Expected error for missing CA file")
+		} else if !strings.Contains(err.Error(), "CA file") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
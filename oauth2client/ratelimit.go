@@ -0,0 +1,300 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request would have to wait for the rate
+// limiter beyond the deadline of its context.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimiter is a token bucket rate limiter. It is safe for concurrent use
+// and may be shared between clients.
+type RateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows requestsPerSecond requests
+// on average, with bursts of up to burst requests. requestsPerSecond must be
+// positive; WithRateLimit and WithPathRateLimit reject a limiter without a
+// positive rate.
+//
+// Example:
+//
+//	// 10 requests per second, bursts of 20
+//	limiter := oauth2client.NewRateLimiter(10, 20)
+//	client, err := oauth2client.NewAPIClientWithOptions(baseURL, oauth2client.WithRateLimit(limiter))
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// validate reports an error for a nil limiter or one without a positive rate.
+func (l *RateLimiter) validate() error {
+	if l == nil {
+		return errors.New("rate limiter must not be nil")
+	}
+	if !(l.rate > 0) {
+		return fmt.Errorf("rate limiter rate must be positive, got %v", l.rate)
+	}
+	return nil
+}
+
+// Wait blocks until a request may be sent or ctx is done. If ctx has a
+// deadline that would pass before then, Wait returns ErrRateLimited at once.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait := l.reserve()
+	if err := waitFor(ctx, wait); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token from the bucket and returns how long the caller must
+// wait before the token becomes available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 || l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (l *RateLimiter) cancel() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// waitFor sleeps for d unless ctx is done first or its deadline is too close.
+func waitFor(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return ErrRateLimited
+	}
+	return sleepContext(ctx, d)
+}
+
+// QuotaState is the request quota last reported by the server through
+// RateLimit-* or X-RateLimit-* response headers.
+type QuotaState struct {
+	// Limit is the number of requests allowed in the current window, or -1 if unknown.
+	Limit int
+
+	// Remaining is the number of requests left in the current window, or -1 if unknown.
+	Remaining int
+
+	// Reset is when the current window ends. It is zero if unknown.
+	Reset time.Time
+
+	// Updated is when the state was last read from a response.
+	Updated time.Time
+}
+
+// quotaTracker records the server-reported quota and, if adaptive, spaces
+// requests so that the remaining quota lasts until the window resets.
+type quotaTracker struct {
+	mutex    sync.Mutex
+	state    QuotaState
+	known    bool
+	adaptive bool
+	next     time.Time
+}
+
+// update reads the quota headers of resp, if any.
+func (q *quotaTracker) update(resp *http.Response, now time.Time) {
+	state, ok := parseQuotaHeaders(resp.Header, now)
+	if !ok {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.state = state
+	q.known = true
+}
+
+// current returns the last reported quota.
+func (q *quotaTracker) current() (QuotaState, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.state, q.known
+}
+
+// wait blocks until the next request fits the reported quota. It only waits
+// if the tracker is adaptive.
+func (q *quotaTracker) wait(ctx context.Context) error {
+	q.mutex.Lock()
+	now := time.Now()
+	var delay time.Duration
+	if q.adaptive && q.known && q.state.Remaining >= 0 && now.Before(q.state.Reset) {
+		if q.state.Remaining == 0 {
+			delay = q.state.Reset.Sub(now)
+		} else {
+			interval := q.state.Reset.Sub(now) / time.Duration(q.state.Remaining)
+			if q.next.After(now) {
+				delay = q.next.Sub(now)
+			}
+			q.next = now.Add(delay + interval)
+		}
+	}
+	q.mutex.Unlock()
+
+	return waitFor(ctx, delay)
+}
+
+// parseQuotaHeaders reads RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset, falling back to their X-RateLimit-* equivalents. Reset is
+// interpreted as seconds from now, or as a Unix timestamp if it is too large
+// to be a delay.
+func parseQuotaHeaders(header http.Header, now time.Time) (QuotaState, bool) {
+	get := func(name string) string {
+		if value := header.Get("RateLimit-" + name); value != "" {
+			return value
+		}
+		return header.Get("X-RateLimit-" + name)
+	}
+
+	state := QuotaState{Limit: -1, Remaining: -1, Updated: now}
+	found := false
+	if limit, err := strconv.Atoi(firstListItem(get("Limit"))); err == nil {
+		state.Limit = limit
+		found = true
+	}
+	if remaining, err := strconv.Atoi(firstListItem(get("Remaining"))); err == nil {
+		state.Remaining = remaining
+		found = true
+	}
+	if reset, err := strconv.ParseInt(firstListItem(get("Reset")), 10, 64); err == nil && reset >= 0 {
+		if reset > 1000000000 {
+			state.Reset = time.Unix(reset, 0)
+		} else {
+			state.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+		found = true
+	}
+	return state, found
+}
+
+// firstListItem returns the first element of a comma-separated header value,
+// such as "100, 100;w=60".
+func firstListItem(value string) string {
+	if i := strings.IndexAny(value, ",;"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// pathRateLimiter applies a RateLimiter to requests whose path starts with prefix.
+type pathRateLimiter struct {
+	prefix  string
+	limiter *RateLimiter
+}
+
+// WithRateLimit limits the rate of all requests sent by the client, including retries.
+func WithRateLimit(limiter *RateLimiter) ClientOption {
+	return func(o *clientOptions) error {
+		if err := limiter.validate(); err != nil {
+			return err
+		}
+		o.rateLimiter = limiter
+		return nil
+	}
+}
+
+// WithPathRateLimit limits the rate of requests whose path, relative to the
+// base URL, starts with prefix. If several prefixes match, the longest one
+// applies, in addition to any limiter set with WithRateLimit.
+func WithPathRateLimit(prefix string, limiter *RateLimiter) ClientOption {
+	return func(o *clientOptions) error {
+		if err := limiter.validate(); err != nil {
+			return err
+		}
+		o.pathRateLimiters = append(o.pathRateLimiters, pathRateLimiter{prefix: prefix, limiter: limiter})
+		return nil
+	}
+}
+
+// WithAdaptiveRateLimit paces requests according to the quota reported by
+// the server in RateLimit-* or X-RateLimit-* headers, spreading the remaining
+// requests over the rest of the window and pausing once the quota is used up.
+func WithAdaptiveRateLimit() ClientOption {
+	return func(o *clientOptions) error {
+		o.adaptiveRateLimit = true
+		return nil
+	}
+}
+
+// RateLimitState returns the request quota last reported by the server in
+// RateLimit-* or X-RateLimit-* headers. It returns false if no response has
+// carried such headers yet.
+func (c *APIClient) RateLimitState() (QuotaState, bool) {
+	return c.quota.current()
+}
+
+// waitRateLimit blocks until req may be sent according to the client's rate limiters.
+func (c *APIClient) waitRateLimit(req *http.Request) error {
+	ctx := req.Context()
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if limiter := c.pathRateLimiter(req.URL); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return c.quota.wait(ctx)
+}
+
+// pathRateLimiter returns the limiter with the longest prefix matching u.
+func (c *APIClient) pathRateLimiter(u *url.URL) *RateLimiter {
+	if len(c.pathRateLimiters) == 0 {
+		return nil
+	}
+	path := u.Path
+	if base, err := url.Parse(c.baseURL); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+
+	var match *pathRateLimiter
+	for i, l := range c.pathRateLimiters {
+		if strings.HasPrefix(path, l.prefix) && (match == nil || len(l.prefix) > len(match.prefix)) {
+			match = &c.pathRateLimiters[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return match.limiter
+}
@@ -0,0 +1,228 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer apiServer.Close()
+
+	t.Run("Token bucket", func(t *testing.T) {
+		limiter := NewRateLimiter(20, 2)
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRateLimit(limiter))
+
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		// Two requests fit the burst; the other two wait 50ms each.
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("Requests were not rate limited, took %v", elapsed)
+		}
+	})
+
+	t.Run("Fail fast on deadline", func(t *testing.T) {
+		limiter := NewRateLimiter(1, 1)
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRateLimit(limiter))
+		if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := client.CallAPIWithContext(ctx, HttpGet, "/", nil, nil)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("Expected to fail fast, took %v", elapsed)
+		}
+
+		// The token was not consumed by the failed call.
+		limiter.mutex.Lock()
+		tokens := limiter.tokens
+		limiter.mutex.Unlock()
+		if tokens < -0.01 {
+			t.Errorf("Unexpected tokens after failed wait: %v", tokens)
+		}
+	})
+
+	t.Run("Request body is closed", func(t *testing.T) {
+		limiter := NewRateLimiter(1, 1)
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRateLimit(limiter))
+		limiter.Wait(context.Background())
+
+		var bodies closeCounter
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, _, err := client.CallAPIWithContext(ctx, HttpPut, "/", bodies.body(), nil); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+		if opened, closed := bodies.counts(); opened != closed {
+			t.Errorf("Expected every body to be closed, opened %d, closed %d", opened, closed)
+		}
+	})
+
+	t.Run("Invalid limiters", func(t *testing.T) {
+		if _, err := NewAPIClientWithOptions(apiServer.URL, WithRateLimit(NewRateLimiter(0, 5))); err == nil {
+			t.Error("Expected error for a zero rate")
+		}
+		if _, err := NewAPIClientWithOptions(apiServer.URL, WithPathRateLimit("/search", NewRateLimiter(-1, 5))); err == nil {
+			t.Error("Expected error for a negative rate")
+		}
+		if _, err := NewAPIClientWithOptions(apiServer.URL, WithRateLimit(nil)); err == nil {
+			t.Error("Expected error for a nil limiter")
+		}
+	})
+
+	t.Run("Path prefix", func(t *testing.T) {
+		slow := NewRateLimiter(1, 1)
+		client, _ := NewAPIClientWithOptions(apiServer.URL+"/api",
+			WithPathRateLimit("/search", slow),
+			WithPathRateLimit("/", NewRateLimiter(1000, 100)),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, _, err := client.CallAPIWithContext(ctx, HttpGet, "/search?q=a", nil, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, _, err := client.CallAPIWithContext(ctx, HttpGet, "/search?q=b", nil, nil); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			if _, _, err := client.CallAPIWithContext(ctx, HttpGet, "/users", nil, nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+// closeCounter counts the request bodies it opens and how many are closed.
+type closeCounter struct {
+	opened, closed int32
+}
+
+// body returns a replayable request body whose readers are counted.
+func (c *closeCounter) body() *RequestBody {
+	return ReplayableBody(func() (io.ReadCloser, error) {
+		atomic.AddInt32(&c.opened, 1)
+		return &countedReader{Reader: strings.NewReader("payload"), counter: c}, nil
+	}, "text/plain", int64(len("payload")))
+}
+
+func (c *closeCounter) counts() (int32, int32) {
+	return atomic.LoadInt32(&c.opened), atomic.LoadInt32(&c.closed)
+}
+
+type countedReader struct {
+	io.Reader
+	counter *closeCounter
+	once    sync.Once
+}
+
+func (r *countedReader) Close() error {
+	r.once.Do(func() { atomic.AddInt32(&r.counter.closed, 1) })
+	return nil
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	t.Run("Parse", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			header http.Header
+			want   QuotaState
+			ok     bool
+		}{
+			{
+				name:   "IETF headers",
+				header: http.Header{"Ratelimit-Limit": {"100, 100;w=60"}, "Ratelimit-Remaining": {"42"}, "Ratelimit-Reset": {"30"}},
+				want:   QuotaState{Limit: 100, Remaining: 42, Reset: now.Add(30 * time.Second)},
+				ok:     true,
+			},
+			{
+				name:   "X-RateLimit with epoch reset",
+				header: http.Header{"X-Ratelimit-Limit": {"5000"}, "X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1700000120"}},
+				want:   QuotaState{Limit: 5000, Remaining: 0, Reset: time.Unix(1700000120, 0)},
+				ok:     true,
+			},
+			{
+				name:   "Remaining only",
+				header: http.Header{"X-Ratelimit-Remaining": {"7"}},
+				want:   QuotaState{Limit: -1, Remaining: 7},
+				ok:     true,
+			},
+			{
+				name:   "No headers",
+				header: http.Header{},
+				ok:     false,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, ok := parseQuotaHeaders(tt.header, now)
+				if ok != tt.ok {
+					t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+				}
+				if !ok {
+					return
+				}
+				if got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining || !got.Reset.Equal(tt.want.Reset) {
+					t.Errorf("Unexpected state: %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("Client state and pacing", func(t *testing.T) {
+		remaining := 1
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("RateLimit-Limit", "10")
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", "1")
+			remaining = 0
+			w.Write([]byte("ok"))
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithAdaptiveRateLimit())
+		if _, ok := client.RateLimitState(); ok {
+			t.Error("Expected no quota state before the first response")
+		}
+		if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, _, err := client.CallAPI(HttpGet, "/", nil, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		state, ok := client.RateLimitState()
+		if !ok || state.Limit != 10 || state.Remaining != 0 {
+			t.Fatalf("Unexpected quota state: %+v, %v", state, ok)
+		}
+
+		// The quota is used up, so the next call must wait for the reset.
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, _, err := client.CallAPIWithContext(ctx, HttpGet, "/", nil, nil); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+	})
+}
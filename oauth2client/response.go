@@ -0,0 +1,105 @@
+package oauth2client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Response is the result of an API call made with CallAPIWithResponse.
+type Response struct {
+	// StatusCode is the HTTP status code of the final response.
+	StatusCode int
+
+	// Header holds the response headers, such as Location, ETag or Link.
+	Header http.Header
+
+	// Body is the response body, decompressed if it was gzip-encoded.
+	Body []byte
+
+	// URL is the URL of the final request, after any redirects.
+	URL *url.URL
+
+	// Duration is the time from sending the first request to reading the
+	// whole response body, including retries and backoff.
+	Duration time.Duration
+
+	// Attempts is the number of attempts made under the client's RetryPolicy.
+	// It is 1 if the request was not retried.
+	Attempts int
+
+	// AuthRetries is the number of times the request was sent again with
+	// refreshed credentials after a 401 Unauthorized response.
+	AuthRetries int
+}
+
+// CallAPIWithResponse makes an authenticated API call and returns the full response.
+//
+// Parameters:
+//   - ctx: A context.Context for controlling cancellation and timeouts
+//   - method: The HTTP method to use (e.g., HttpGet, HttpPost, HttpPut, HttpDelete)
+//   - path: The API endpoint path (will be appended to the base URL)
+//   - body: The request body. Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
+//   - additionalHeaders: Additional HTTP headers to include in the request
+//   - opts: Per-call options, such as WithCallStatusPolicy
+//
+// Returns:
+//   - *Response: The response status, headers, body and call statistics. It is nil if no
+//     response was received.
+//   - error: Any error that occurred during the request. An unsuccessful status is reported
+//     as an *APIError. The Response is returned along with the error whenever a response
+//     was received.
+//
+// Example:
+//
+//	response, err := client.CallAPIWithResponse(ctx, oauth2client.HttpPost, "/users", newUser, nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Printf("Created %s (ETag %s)\n", response.Header.Get("Location"), response.Header.Get("ETag"))
+func (c *APIClient) CallAPIWithResponse(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, opts ...CallOption) (*Response, error) {
+	return c.callURL(ctx, method, c.baseURL+path, body, additionalHeaders, opts...)
+}
+
+// callURL is CallAPIWithResponse for an absolute URL. Callers must make sure
+// that the URL may receive the client's credentials.
+func (c *APIClient) callURL(ctx context.Context, method HttpMethod, rawURL string, body interface{}, additionalHeaders map[string]string, opts ...CallOption) (*Response, error) {
+	options := c.callOptions(opts)
+	req, err := c.newRequestURL(ctx, method, rawURL, body, additionalHeaders)
+	if err != nil {
+		return nil, err
+	}
+	options.applyHeaders(req)
+
+	start := time.Now()
+	var stats callStats
+	resp, err := c.do(req, &stats)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &Response{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		URL:         req.URL,
+		Attempts:    stats.attempts,
+		AuthRetries: stats.sent - stats.attempts,
+	}
+	if resp.Request != nil {
+		response.URL = resp.Request.URL
+	}
+
+	response.Body, err = readResponseBody(resp)
+	response.Duration = time.Since(start)
+	if err != nil {
+		return response, err
+	}
+
+	if !options.statusPolicy(resp.StatusCode) {
+		return response, newAPIError(resp, response.Body)
+	}
+
+	return response, nil
+}
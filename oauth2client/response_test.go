@@ -0,0 +1,101 @@
+package oauth2client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallAPIWithResponse(t *testing.T) {
+	t.Run("Headers and final URL", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		})
+		mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Link", `</new?page=2>; rel="next"`)
+			w.Write([]byte("moved"))
+		})
+		apiServer := httptest.NewServer(mux)
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		response, err := client.CallAPIWithResponse(context.Background(), HttpGet, "/old", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.StatusCode != http.StatusOK || string(response.Body) != "moved" {
+			t.Errorf("Unexpected response: %d %s", response.StatusCode, response.Body)
+		}
+		if response.Header.Get("ETag") != `"v1"` || response.Header.Get("Link") == "" {
+			t.Errorf("Unexpected headers: %v", response.Header)
+		}
+		if response.URL.Path != "/new" {
+			t.Errorf("Unexpected final URL: %s", response.URL)
+		}
+		if response.Attempts != 1 || response.AuthRetries != 0 || response.Duration <= 0 {
+			t.Errorf("Unexpected statistics: %+v", response)
+		}
+	})
+
+	t.Run("Attempts", func(t *testing.T) {
+		var tokenRequests, apiRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tokenRequests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "token_" + string(rune('0'+n)),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		}))
+		defer tokenServer.Close()
+
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&apiRequests, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				w.WriteHeader(http.StatusCreated)
+			}
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL,
+			WithOAuth2Config(OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		)
+		response, err := client.CallAPIWithResponse(context.Background(), HttpGet, "/", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.StatusCode != http.StatusCreated || response.Attempts != 2 || response.AuthRetries != 1 {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "abc")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("missing"))
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		response, err := client.CallAPIWithResponse(context.Background(), HttpGet, "/", nil, nil)
+		if err == nil {
+			t.Fatal("Expected error for 404 response")
+		}
+		if response == nil || response.StatusCode != http.StatusNotFound || response.Header.Get("X-Request-Id") != "abc" || string(response.Body) != "missing" {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+	})
+}
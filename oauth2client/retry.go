@@ -0,0 +1,186 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls automatic retries of failed requests.
+//
+// A request is retried when sending it fails with a transient network error,
+// such as a connection reset, or when the response status is one of
+// RetryableStatusCodes. Only idempotent requests are retried unless
+// RetryNonIdempotent is set; a request carrying an Idempotency-Key header
+// counts as idempotent. Request bodies are rewound between attempts, so a
+// request whose body cannot be replayed is never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry. Defaults to 200ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the backoff grows after each attempt. Defaults to 2.
+	Multiplier float64
+
+	// Jitter is the fraction of each backoff, between 0 and 1, that is
+	// randomized to spread out retries from many clients.
+	Jitter float64
+
+	// RetryableStatusCodes lists the response statuses that are retried.
+	// Defaults to 429, 502, 503 and 504.
+	RetryableStatusCodes []int
+
+	// MaxRetryAfter caps the wait requested by a Retry-After header. If the
+	// server asks for a longer wait, the response is returned instead.
+	// Defaults to one minute.
+	MaxRetryAfter time.Duration
+
+	// RetryNonIdempotent allows retrying POST and PATCH requests.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy that makes up to 3 attempts with
+// exponential backoff starting at 200ms and 20% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Jitter:      0.2,
+	}
+}
+
+// WithRetryPolicy retries failed requests according to policy.
+//
+// Example:
+//
+//	policy := oauth2client.DefaultRetryPolicy()
+//	policy.MaxAttempts = 5
+//	client, err := oauth2client.NewAPIClientWithOptions(baseURL, oauth2client.WithRetryPolicy(policy))
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) error {
+		o.retryPolicy = policy.withDefaults()
+		return nil
+	}
+}
+
+func (p RetryPolicy) withDefaults() *RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 200 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.RetryableStatusCodes == nil {
+		p.RetryableStatusCodes = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = time.Minute
+	}
+	p.Jitter = math.Max(0, math.Min(1, p.Jitter))
+	return &p
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying.
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if !p.RetryNonIdempotent && !isIdempotent(req) {
+		return false
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the wait before the next attempt and whether to retry at all.
+// A Retry-After header on resp takes precedence over the exponential backoff.
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return wait, wait <= p.MaxRetryAfter
+		}
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(p.MaxBackoff))
+	backoff -= backoff * p.Jitter * rand.Float64()
+	return time.Duration(backoff), true
+}
+
+// parseRetryAfter parses a Retry-After value given either in seconds or as an HTTP-date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := t.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether req may safely be sent more than once.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// isTransientError reports whether err is a network failure that may succeed on retry.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return isConnectionError(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package oauth2client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	fastPolicy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	t.Run("Retryable status", func(t *testing.T) {
+		var requests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&requests, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				body, _ := io.ReadAll(r.Body)
+				w.Write(body)
+			}
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(fastPolicy))
+		response, statusCode, err := client.CallAPI(HttpPut, "/", "payload", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if statusCode != http.StatusOK || string(response) != "payload" {
+			t.Errorf("Unexpected response: %d %s", statusCode, response)
+		}
+		if n := atomic.LoadInt32(&requests); n != 3 {
+			t.Errorf("Expected 3 requests, got %d", n)
+		}
+	})
+
+	t.Run("Attempts exhausted", func(t *testing.T) {
+		var requests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(fastPolicy))
+		_, statusCode, err := client.CallAPI(HttpGet, "/", nil, nil)
+		if err == nil || statusCode != http.StatusBadGateway {
+			t.Errorf("Expected 502 error, got %d, %v", statusCode, err)
+		}
+		if n := atomic.LoadInt32(&requests); n != 3 {
+			t.Errorf("Expected 3 requests, got %d", n)
+		}
+	})
+
+	t.Run("Non-idempotent requests", func(t *testing.T) {
+		var requests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(fastPolicy))
+		if _, statusCode, _ := client.CallAPI(HttpPost, "/", map[string]string{"key": "value"}, nil); statusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected POST not to be retried, got status %d", statusCode)
+		}
+
+		atomic.StoreInt32(&requests, 0)
+		headers := map[string]string{"Idempotency-Key": "abc"}
+		if _, statusCode, err := client.CallAPI(HttpPost, "/", map[string]string{"key": "value"}, headers); err != nil || statusCode != http.StatusCreated {
+			t.Errorf("Expected POST with Idempotency-Key to be retried, got %d, %v", statusCode, err)
+		}
+	})
+
+	t.Run("Connection reset", func(t *testing.T) {
+		var requests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(fastPolicy))
+		response, _, err := client.CallAPI(HttpGet, "/", nil, nil)
+		if err != nil || string(response) != "ok" {
+			t.Errorf("Unexpected result: %s, %v", response, err)
+		}
+	})
+
+	t.Run("Canceled during backoff", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer apiServer.Close()
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(fastPolicy))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var bodies closeCounter
+		if _, _, err := client.CallAPIWithContext(ctx, HttpPut, "/", bodies.body(), nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+		}
+		if opened, closed := bodies.counts(); opened != 2 || closed != 2 {
+			t.Errorf("Expected the rewound body to be closed, opened %d, closed %d", opened, closed)
+		}
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		tests := []struct {
+			value string
+			want  time.Duration
+			ok    bool
+		}{
+			{"120", 120 * time.Second, true},
+			{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+			{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+			{"-1", 0, false},
+			{"soon", 0, false},
+		}
+		for _, tt := range tests {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		}
+
+		policy := RetryPolicy{MaxRetryAfter: time.Minute}.withDefaults()
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+		if _, ok := policy.delay(1, resp); ok {
+			t.Error("Expected Retry-After above MaxRetryAfter to stop retrying")
+		}
+	})
+}
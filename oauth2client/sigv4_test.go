@@ -0,0 +1,157 @@
+package oauth2client
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSSigV4Auth(t *testing.T) {
+	// Test vectors from the AWS Signature Version 4 test suite and the
+	// IAM examples in the AWS General Reference.
+	signingTime := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	credentials := StaticAWSCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "")
+
+	t.Run("Signing key", func(t *testing.T) {
+		key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+		want := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+		if got := hex.EncodeToString(key); got != want {
+			t.Errorf("Unexpected signing key:\n got %s\nwant %s", got, want)
+		}
+	})
+
+	t.Run("Canonical query", func(t *testing.T) {
+		tests := map[string]string{
+			"a1=3&a-b=2&a=1":       "a=1&a-b=2&a1=3",
+			"b=2&a.b=1&a=2&a=10":   "a=10&a=2&a.b=1&b=2",
+			"key=a+b&key=a%2Fb&k=": "k=&key=a%20b&key=a%2Fb",
+		}
+		for rawQuery, want := range tests {
+			if got := awsCanonicalQuery(&url.URL{RawQuery: rawQuery}); got != want {
+				t.Errorf("awsCanonicalQuery(%q) = %q, want %q", rawQuery, got, want)
+			}
+		}
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		service string
+		headers map[string]string
+		body    string
+		want    string
+	}{
+		{
+			name:    "get-vanilla",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "post-vanilla",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:    "get-vanilla-query-order-key-case",
+			method:  "GET",
+			url:     "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			service: "service",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:    "post-x-www-form-urlencoded",
+			method:  "POST",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:    "Param1=value1",
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:    "iam-list-users",
+			method:  "GET",
+			url:     "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			service: "iam",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			want:    "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.body == "" {
+				req.Body = http.NoBody
+			}
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			auth := &AWSSigV4Auth{
+				Credentials: credentials,
+				Region:      "us-east-1",
+				Service:     tt.service,
+				now:         func() time.Time { return signingTime },
+			}
+			if err := auth.Authenticate(req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Unexpected Authorization header:\n got %s\nwant %s", got, tt.want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("Unexpected X-Amz-Date header: %s", got)
+			}
+		})
+	}
+
+	t.Run("Shared credentials file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "credentials")
+		content := "[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = default-secret\n\n" +
+			"# temporary credentials\n[ci]\naws_access_key_id=AKIDCI\naws_secret_access_key=ci-secret\naws_session_token=ci-token\n"
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write credentials file: %v", err)
+		}
+
+		credentials, err := (&AWSSharedCredentialsFile{Path: path, Profile: "ci"}).Retrieve()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if credentials != (AWSCredentials{AccessKeyID: "AKIDCI", SecretAccessKey: "ci-secret", SessionToken: "ci-token"}) {
+			t.Errorf("Unexpected credentials: %+v", credentials)
+		}
+
+		if _, err := (&AWSSharedCredentialsFile{Path: path, Profile: "missing"}).Retrieve(); err == nil {
+			t.Error("Expected error for missing profile")
+		}
+
+		req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+		auth := &AWSSigV4Auth{
+			Credentials: &AWSSharedCredentialsFile{Path: path, Profile: "ci"},
+			Region:      "us-east-1",
+			Service:     "execute-api",
+		}
+		if err := auth.Authenticate(req); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if req.Header.Get("X-Amz-Security-Token") != "ci-token" {
+			t.Errorf("Unexpected X-Amz-Security-Token header: %s", req.Header.Get("X-Amz-Security-Token"))
+		}
+		if !strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+			t.Errorf("Unexpected Authorization header: %s", req.Header.Get("Authorization"))
+		}
+	})
+}
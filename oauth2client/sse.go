@@ -0,0 +1,264 @@
+package oauth2client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSSERetry is the reconnection delay until the server sets one.
+const defaultSSERetry = 3 * time.Second
+
+// maxSSERetry caps the reconnection delay a server can set.
+const maxSSERetry = time.Hour
+
+// maxSSELineSize limits the length of a single line in an event stream.
+const maxSSELineSize = 16 << 20
+
+// ServerSentEvent is an event received from a text/event-stream response.
+type ServerSentEvent struct {
+	// ID is the last event ID set by the stream, which is sent as
+	// Last-Event-ID when reconnecting.
+	ID string
+
+	// Type is the event type, "message" unless the event set another one.
+	Type string
+
+	// Data is the event data, with multiple data lines joined by "\n".
+	Data string
+}
+
+// WithLastEventID resumes an event stream after the event with the given ID
+// by sending it as the Last-Event-ID header of the first connection.
+func WithLastEventID(id string) CallOption {
+	return func(o *callOptions) {
+		o.lastEventID = id
+	}
+}
+
+// StreamEvents subscribes to the Server-Sent Events stream at path and calls
+// handler for each event until ctx is cancelled, handler returns an error or
+// the server ends the stream with 204 No Content.
+//
+// When the connection drops or fails with a network error, or the server
+// responds with a retryable status, StreamEvents reconnects after the delay
+// set by the server's retry field (3 seconds by default, at most an hour),
+// sending the ID of the last event as Last-Event-ID. Every connection is
+// authenticated anew, so an expired token is refreshed.
+//
+// Returns:
+//   - error: ctx.Err() after cancellation, the error returned by handler, or the
+//     error that stopped the stream, such as an *APIError with a non-retryable
+//     status or a failure to obtain a token.
+//
+// Example:
+//
+//	err := client.StreamEvents(ctx, "/notifications", func(event oauth2client.ServerSentEvent) error {
+//		log.Printf("%s: %s", event.Type, event.Data)
+//		return nil
+//	})
+func (c *APIClient) StreamEvents(ctx context.Context, path string, handler func(ServerSentEvent) error, opts ...CallOption) error {
+	parser := &eventParser{
+		lastEventID: c.callOptions(opts).lastEventID,
+		retry:       defaultSSERetry,
+	}
+	opts = append(opts[:len(opts):len(opts)], WithCallStatusPolicy(AcceptStatusCodes(http.StatusOK, http.StatusNoContent)))
+
+	for {
+		headers := map[string]string{
+			"Accept":        "text/event-stream",
+			"Cache-Control": "no-cache",
+		}
+		if parser.lastEventID != "" {
+			headers["Last-Event-ID"] = parser.lastEventID
+		}
+
+		stream, statusCode, err := c.CallAPIStream(ctx, HttpGet, path, nil, headers, opts...)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isReconnectable(err) {
+				return err
+			}
+		case statusCode == http.StatusNoContent:
+			stream.Close()
+			return nil
+		default:
+			err = parser.parse(stream, handler)
+			stream.Close()
+			var stop handlerError
+			if errors.As(err, &stop) {
+				return stop.err
+			}
+			if errors.Is(err, bufio.ErrTooLong) {
+				return err
+			}
+		}
+
+		if err := sleepContext(ctx, parser.retry); err != nil {
+			return err
+		}
+	}
+}
+
+// isReconnectable reports whether a failed connection attempt may succeed
+// later: a network error while sending the request or reading the response,
+// or a retryable status. Errors such as an invalid URL or a rejected token
+// request are not.
+func isReconnectable(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Op != "parse"
+}
+
+// Events subscribes to the Server-Sent Events stream at path like
+// StreamEvents, delivering events on the returned channel. When the stream
+// stops, the error that stopped it is sent on the error channel and both
+// channels are closed.
+//
+// Example:
+//
+//	events, errs := client.Events(ctx, "/notifications")
+//	for event := range events {
+//		log.Printf("%s: %s", event.Type, event.Data)
+//	}
+//	if err := <-errs; err != nil && !errors.Is(err, context.Canceled) {
+//		log.Fatal(err)
+//	}
+func (c *APIClient) Events(ctx context.Context, path string, opts ...CallOption) (<-chan ServerSentEvent, <-chan error) {
+	events := make(chan ServerSentEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		err := c.StreamEvents(ctx, path, func(event ServerSentEvent) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return events, errs
+}
+
+// handlerError marks an error returned by an event handler.
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string {
+	return e.err.Error()
+}
+
+// eventParser parses text/event-stream bodies as specified by the HTML
+// Living Standard, keeping the last event ID and reconnection delay across
+// connections.
+type eventParser struct {
+	lastEventID string
+	retry       time.Duration
+}
+
+// parse reads events from r until it ends, calling handler for each one.
+// Errors from handler are returned as a handlerError.
+func (p *eventParser) parse(r io.Reader, handler func(ServerSentEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxSSELineSize)
+	scanner.Split(scanEventLines)
+
+	var eventType string
+	var data strings.Builder
+	hasData := false
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+
+		if line == "" {
+			if hasData {
+				event := ServerSentEvent{ID: p.lastEventID, Type: eventType, Data: data.String()}
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				if err := handler(event); err != nil {
+					return handlerError{err: err}
+				}
+			}
+			eventType = ""
+			data.Reset()
+			hasData = false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				p.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 64); err == nil {
+				if ms > uint64(maxSSERetry/time.Millisecond) {
+					ms = uint64(maxSSERetry / time.Millisecond)
+				}
+				p.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanEventLines is a bufio.SplitFunc for lines ending in "\r\n", "\n" or "\r".
+// An incomplete event at the end of the stream is not returned.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	i := bytes.IndexAny(data, "\r\n")
+	if i < 0 {
+		return 0, nil, nil
+	}
+	if data[i] == '\n' {
+		return i + 1, data[:i], nil
+	}
+	if i+1 < len(data) {
+		if data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return i + 1, data[:i], nil
+	}
+	// A "\r" at the end of the buffer may be followed by "\n".
+	return 0, nil, nil
+}
@@ -0,0 +1,213 @@
+package oauth2client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventParser(t *testing.T) {
+	input := "\uFEFF: comment\r\n" +
+		"retry: 250\r\n" +
+		"data: first\r\n" +
+		"data:  second line\r\n" +
+		"\r\n" +
+		"event: update\n" +
+		"id: 42\n" +
+		"data\n" +
+		"\n" +
+		"id\rdata: no id\r\r" +
+		"data: no blank line before EOF"
+
+	parser := &eventParser{retry: defaultSSERetry}
+	var events []ServerSentEvent
+	err := parser.parse(strings.NewReader(input), func(event ServerSentEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []ServerSentEvent{
+		{Type: "message", Data: "first\n second line"},
+		{ID: "42", Type: "update", Data: ""},
+		{ID: "", Type: "message", Data: "no id"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Unexpected events: %+v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Event %d: got %+v, want %+v", i, events[i], want[i])
+		}
+	}
+	if parser.retry != 250*time.Millisecond {
+		t.Errorf("Unexpected retry: %v", parser.retry)
+	}
+
+	parser.parse(strings.NewReader("retry: 18446744073709551615\n\n"), func(ServerSentEvent) error { return nil })
+	if parser.retry != maxSSERetry {
+		t.Errorf("Expected a large retry to be capped, got %v", parser.retry)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	t.Run("Reconnect with Last-Event-ID and fresh token", func(t *testing.T) {
+		var tokenRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tokenRequests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			// Tokens are refreshed 60 seconds before they expire, so this one is
+			// expired by the time the stream reconnects.
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "token_" + string(rune('0'+n)),
+				"token_type":   "Bearer",
+				"expires_in":   60,
+			})
+		}))
+		defer tokenServer.Close()
+
+		var mutex sync.Mutex
+		var connections []string
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			connections = append(connections, r.Header.Get("Authorization")+" "+r.Header.Get("Last-Event-ID"))
+			n := len(connections)
+			mutex.Unlock()
+
+			if r.Header.Get("Accept") != "text/event-stream" {
+				t.Errorf("Unexpected Accept header: %q", r.Header.Get("Accept"))
+			}
+			switch n {
+			case 1:
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("retry: 10\nid: 1\ndata: one\n\n"))
+			case 2:
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("id: 2\nevent: update\ndata: two\n\n"))
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}, apiServer.URL)
+		var events []ServerSentEvent
+		err := client.StreamEvents(context.Background(), "/events", func(event ServerSentEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(events) != 2 || events[0].Data != "one" || events[1].ID != "2" || events[1].Type != "update" {
+			t.Errorf("Unexpected events: %+v", events)
+		}
+		want := []string{"Bearer token_1 ", "Bearer token_2 1", "Bearer token_3 2"}
+		if strings.Join(connections, ", ") != strings.Join(want, ", ") {
+			t.Errorf("Unexpected connections:\n got %q\nwant %q", connections, want)
+		}
+	})
+
+	t.Run("Reconnect after a transport error", func(t *testing.T) {
+		var connections int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&connections, 1) {
+			case 1:
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("retry: 10\ndata: one\n\n"))
+			case 2:
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Write([]byte("not HTTP\r\n\r\n"))
+				conn.Close()
+			case 3:
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("data: two\n\n"))
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		var data []string
+		err := client.StreamEvents(context.Background(), "/events", func(event ServerSentEvent) error {
+			data = append(data, event.Data)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Join(data, " ") != "one two" {
+			t.Errorf("Unexpected events: %q", data)
+		}
+	})
+
+	t.Run("Non-retryable status", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		err := client.StreamEvents(context.Background(), "/events", func(ServerSentEvent) error { return nil })
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403 APIError, got %v", err)
+		}
+	})
+
+	t.Run("Permanent errors", func(t *testing.T) {
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+		}))
+		defer tokenServer.Close()
+
+		clients := map[string]*APIClient{
+			"rejected token request": NewAPIClient(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "wrong"}, "http://127.0.0.1:1"),
+			"malformed base URL":     NewAPIClient(nil, "http://[::1"),
+		}
+		for name, client := range clients {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			err := client.StreamEvents(ctx, "/events", func(ServerSentEvent) error { return nil })
+			cancel()
+			if err == nil || errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%s: expected the cause to be returned, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("Channel delivery until cancelled", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("retry: 10\ndata: tick\n\n"))
+		}))
+		defer apiServer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := NewAPIClient(nil, apiServer.URL)
+		events, errs := client.Events(ctx, "/events", WithLastEventID("start"))
+
+		for i := 0; i < 3; i++ {
+			if event := <-events; event.Data != "tick" {
+				t.Errorf("Unexpected event: %+v", event)
+			}
+		}
+		cancel()
+		for range events {
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
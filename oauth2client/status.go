@@ -0,0 +1,48 @@
+package oauth2client
+
+// StatusPolicy reports whether a response status code counts as success.
+// Responses with other status codes are returned as an *APIError.
+type StatusPolicy func(statusCode int) bool
+
+// DefaultStatusPolicy accepts all 2xx status codes.
+func DefaultStatusPolicy(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+// AcceptStatusCodes returns a StatusPolicy that accepts exactly the given status codes.
+//
+// Example:
+//
+//	// Treat 304 Not Modified as success for conditional requests
+//	policy := oauth2client.AcceptStatusCodes(http.StatusOK, http.StatusNotModified)
+func AcceptStatusCodes(statusCodes ...int) StatusPolicy {
+	accepted := make(map[int]bool, len(statusCodes))
+	for _, statusCode := range statusCodes {
+		accepted[statusCode] = true
+	}
+	return func(statusCode int) bool {
+		return accepted[statusCode]
+	}
+}
+
+// WithStatusPolicy sets which status codes the client treats as success.
+// The default is DefaultStatusPolicy.
+func WithStatusPolicy(policy StatusPolicy) ClientOption {
+	return func(o *clientOptions) error {
+		o.statusPolicy = policy
+		return nil
+	}
+}
+
+// WithCallStatusPolicy sets which status codes count as success for one call,
+// overriding the client's StatusPolicy.
+//
+// Example:
+//
+//	response, err := client.CallAPIWithResponse(ctx, oauth2client.HttpGet, "/items", nil, headers,
+//		oauth2client.WithCallStatusPolicy(oauth2client.AcceptStatusCodes(http.StatusOK, http.StatusNotModified)))
+func WithCallStatusPolicy(policy StatusPolicy) CallOption {
+	return func(o *callOptions) {
+		o.statusPolicy = policy
+	}
+}
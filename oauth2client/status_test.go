@@ -0,0 +1,68 @@
+package oauth2client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestStatusPolicy(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statusCode, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(statusCode)
+		if statusCode != http.StatusNoContent && statusCode != http.StatusNotModified {
+			w.Write([]byte("content"))
+		}
+	}))
+	defer apiServer.Close()
+
+	t.Run("Default accepts 2xx", func(t *testing.T) {
+		client := NewAPIClient(nil, apiServer.URL)
+		for _, statusCode := range []int{200, 201, 202, 204, 206} {
+			_, got, err := client.CallAPI(HttpDelete, "/?status="+strconv.Itoa(statusCode), nil, nil)
+			if err != nil || got != statusCode {
+				t.Errorf("Status %d: unexpected result %d, %v", statusCode, got, err)
+			}
+		}
+		for _, statusCode := range []int{304, 400, 500} {
+			_, got, err := client.CallAPI(HttpGet, "/?status="+strconv.Itoa(statusCode), nil, nil)
+			if err == nil || got != statusCode {
+				t.Errorf("Status %d: expected error, got %d, %v", statusCode, got, err)
+			}
+		}
+	})
+
+	t.Run("Client policy", func(t *testing.T) {
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithStatusPolicy(AcceptStatusCodes(http.StatusOK)))
+		if _, _, err := client.CallAPI(HttpGet, "/?status=202", nil, nil); err == nil {
+			t.Error("Expected error for 202 with client policy")
+		}
+		if _, _, err := client.CallAPI(HttpGet, "/?status=200", nil, nil); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Call policy overrides client policy", func(t *testing.T) {
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithStatusPolicy(AcceptStatusCodes(http.StatusOK)))
+		response, err := client.CallAPIWithResponse(context.Background(), HttpGet, "/?status=304", nil, nil,
+			WithCallStatusPolicy(AcceptStatusCodes(http.StatusOK, http.StatusNotModified)))
+		if err != nil || response.StatusCode != http.StatusNotModified {
+			t.Errorf("Unexpected result: %v, %v", response, err)
+		}
+	})
+
+	t.Run("Download accepts partial content", func(t *testing.T) {
+		client := NewAPIClient(nil, apiServer.URL)
+		destPath := filepath.Join(t.TempDir(), "partial")
+		if err := client.DownloadFile(HttpGet, "/?status=206", nil, nil, destPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(destPath); string(data) != "content" {
+			t.Errorf("Unexpected file content: %q", data)
+		}
+	})
+}
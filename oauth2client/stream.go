@@ -0,0 +1,62 @@
+package oauth2client
+
+import (
+	"context"
+	"io"
+)
+
+// CallAPIStream makes an authenticated API call and returns the response body
+// as a stream instead of reading it into memory.
+//
+// Parameters:
+//   - ctx: A context.Context for controlling cancellation and timeouts. Cancelling it
+//     also aborts reading the stream.
+//   - method: The HTTP method to use (e.g., HttpGet, HttpPost, HttpPut, HttpDelete)
+//   - path: The API endpoint path (will be appended to the base URL)
+//   - body: The request body. Can be nil, a string, []byte, url.Values, an io.Reader, a *RequestBody, or any JSON-serializable type
+//   - additionalHeaders: Additional HTTP headers to include in the request
+//   - opts: Per-call options, such as WithCallStatusPolicy
+//
+// Returns:
+//   - io.ReadCloser: The response body, decompressed if it is gzip-encoded. The caller must close it.
+//   - int: The HTTP status code
+//   - error: Any error that occurred during the request. An unsuccessful status is reported
+//     as an *APIError, and no stream is returned.
+//
+// The request is authenticated, refreshed after a 401 Unauthorized response and
+// retried like CallAPI. A client timeout set with WithTimeout also limits the
+// time spent reading the stream; use ctx to bound long downloads instead.
+//
+// Example:
+//
+//	stream, _, err := client.CallAPIStream(ctx, oauth2client.HttpGet, "/exports/orders", nil, nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer stream.Close()
+//	decoder := json.NewDecoder(stream)
+func (c *APIClient) CallAPIStream(ctx context.Context, method HttpMethod, path string, body interface{}, additionalHeaders map[string]string, opts ...CallOption) (io.ReadCloser, int, error) {
+	options := c.callOptions(opts)
+	req, err := c.newRequest(ctx, method, path, body, additionalHeaders)
+	if err != nil {
+		return nil, 0, err
+	}
+	options.applyHeaders(req)
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !options.statusPolicy(resp.StatusCode) {
+		defer resp.Body.Close()
+		responseBody, _ := readResponseBody(resp)
+		return nil, resp.StatusCode, newAPIError(resp, responseBody)
+	}
+
+	stream, err := responseBodyReader(resp)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return stream, resp.StatusCode, nil
+}
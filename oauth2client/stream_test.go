@@ -0,0 +1,109 @@
+package oauth2client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCallAPIStream(t *testing.T) {
+	t.Run("Large body", func(t *testing.T) {
+		const size = 8 << 20
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(w, io.LimitReader(zeroReader{}, size))
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		stream, statusCode, err := client.CallAPIStream(context.Background(), HttpGet, "/export", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer stream.Close()
+		if statusCode != http.StatusOK {
+			t.Errorf("Unexpected status code: %d", statusCode)
+		}
+		n, err := io.Copy(io.Discard, stream)
+		if err != nil || n != size {
+			t.Errorf("Unexpected stream length: %d, %v", n, err)
+		}
+	})
+
+	t.Run("Gzip and token refresh", func(t *testing.T) {
+		var tokenRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tokenRequests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "token_" + string(rune('0'+n)),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		}))
+		defer tokenServer.Close()
+
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token_2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(`{"id":1}` + "\n" + `{"id":2}` + "\n"))
+			gz.Close()
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}, apiServer.URL)
+		stream, _, err := client.CallAPIStream(context.Background(), HttpGet, "/export", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer stream.Close()
+
+		decoder := json.NewDecoder(stream)
+		var ids []int
+		for decoder.More() {
+			var record struct{ ID int }
+			if err := decoder.Decode(&record); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, record.ID)
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("Unexpected records: %v", ids)
+		}
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "export not ready", http.StatusConflict)
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClient(nil, apiServer.URL)
+		stream, statusCode, err := client.CallAPIStream(context.Background(), HttpGet, "/export", nil, nil)
+		if stream != nil || statusCode != http.StatusConflict || !IsConflict(err) {
+			t.Errorf("Unexpected result: %v, %d, %v", stream, statusCode, err)
+		}
+		if !bytes.Contains([]byte(err.Error()), []byte("export not ready")) {
+			t.Errorf("Unexpected error text: %v", err)
+		}
+	})
+}
+
+// zeroReader is an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,97 @@
+package oauth2client
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Token represents an OAuth2 token.
+//
+// Its shape follows the token type of golang.org/x/oauth2, so tokens can be
+// converted between the two packages field by field.
+type Token struct {
+	// AccessToken is the token that authorizes requests.
+	AccessToken string `json:"access_token"`
+
+	// TokenType is the type of the token, usually "Bearer".
+	TokenType string `json:"token_type,omitempty"`
+
+	// RefreshToken is used to obtain a new access token, if the server issued one.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Expiry is when the access token expires. A zero value means it does not expire.
+	Expiry time.Time `json:"expiry,omitempty"`
+
+	// Extra holds additional fields returned by the token endpoint, such as "scope".
+	Extra map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Type returns the Authorization scheme for the token. The MAC and Basic
+// schemes are recognized in any case; every other token type, including
+// non-standard values such as "access_token", is sent as "Bearer".
+func (t *Token) Type() string {
+	switch {
+	case strings.EqualFold(t.TokenType, "mac"):
+		return "MAC"
+	case strings.EqualFold(t.TokenType, "basic"):
+		return "Basic"
+	default:
+		return "Bearer"
+	}
+}
+
+// Valid reports whether t has an access token that has not expired.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// SetAuthHeader sets the Authorization header of req to the token.
+func (t *Token) SetAuthHeader(req *http.Request) {
+	req.Header.Set("Authorization", t.Type()+" "+t.AccessToken)
+}
+
+// TokenSource supplies tokens for authenticating requests.
+//
+// The method set mirrors the TokenSource of golang.org/x/oauth2, so a source
+// from either package can be adapted to the other with a few lines of code.
+// Token must be safe for concurrent use and should return a cached token
+// while it is still valid.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// staleTokenRefresher is implemented by token sources that can replace an
+// access token rejected by the server before it expires.
+type staleTokenRefresher interface {
+	refreshStaleToken(stale string) error
+}
+
+// TokenSource returns a TokenSource that obtains tokens from the token
+// endpoint using the client credentials grant and caches them until they expire.
+//
+// Example:
+//
+//	source := config.TokenSource()
+//	token, err := source.Token()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	token.SetAuthHeader(req)
+func (c *OAuth2Config) TokenSource() TokenSource {
+	return &tokenManager{config: *c}
+}
+
+// StaticTokenSource returns a TokenSource that always returns token.
+// It is useful for long-lived tokens and for testing.
+func StaticTokenSource(token *Token) TokenSource {
+	return staticTokenSource{token: token}
+}
+
+type staticTokenSource struct {
+	token *Token
+}
+
+func (s staticTokenSource) Token() (*Token, error) {
+	return s.token, nil
+}
@@ -0,0 +1,124 @@
+package oauth2client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTokenSource(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access_token_" + string(rune('0'+n)),
+			"token_type":   "bearer",
+			"expires_in":   3600,
+			"scope":        "read write",
+			"tenant":       "acme",
+		})
+	}))
+	defer tokenServer.Close()
+
+	config := OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "test_client_id",
+		ClientSecret: "test_client_secret",
+	}
+
+	t.Run("Config TokenSource", func(t *testing.T) {
+		source := config.TokenSource()
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !token.Valid() {
+			t.Errorf("Expected valid token: %+v", token)
+		}
+		if token.Type() != "Bearer" {
+			t.Errorf("Unexpected token type: %s", token.Type())
+		}
+		if token.Extra["scope"] != "read write" || token.Extra["tenant"] != "acme" {
+			t.Errorf("Unexpected extra fields: %v", token.Extra)
+		}
+		if _, ok := token.Extra["access_token"]; ok {
+			t.Errorf("Extra fields should not contain the access token: %v", token.Extra)
+		}
+
+		again, err := source.Token()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if again.AccessToken != token.AccessToken {
+			t.Errorf("Expected cached token %q, got %q", token.AccessToken, again.AccessToken)
+		}
+	})
+
+	t.Run("Token type", func(t *testing.T) {
+		tests := map[string]string{
+			"":             "Bearer",
+			"bearer":       "Bearer",
+			"mac":          "MAC",
+			"Basic":        "Basic",
+			"access_token": "Bearer",
+			"N_A":          "Bearer",
+		}
+		for tokenType, want := range tests {
+			token := &Token{AccessToken: "abc", TokenType: tokenType}
+			req, _ := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+			token.SetAuthHeader(req)
+			if got := req.Header.Get("Authorization"); got != want+" abc" {
+				t.Errorf("Token type %q: got Authorization %q, want %q", tokenType, got, want+" abc")
+			}
+		}
+	})
+
+	t.Run("Static TokenSource", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer static_token" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClientWithTokenSource(StaticTokenSource(&Token{AccessToken: "static_token"}), apiServer.URL)
+		response, statusCode, err := client.CallAPI(HttpGet, "/", nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if statusCode != http.StatusOK || string(response) != "ok" {
+			t.Errorf("Unexpected response: %d %s", statusCode, response)
+		}
+	})
+
+	t.Run("Refresh after 401", func(t *testing.T) {
+		var apiRequests int32
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reject the first token the client presents.
+			if atomic.AddInt32(&apiRequests, 1) == 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		defer apiServer.Close()
+
+		client := NewAPIClientWithTokenSource(config.TokenSource(), apiServer.URL)
+		response, _, err := client.CallAPI(HttpPost, "/", map[string]string{"key": "value"}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		first, _ := client.tokenManager.getValidToken()
+		if string(response) != "Bearer "+first {
+			t.Errorf("Expected retry with refreshed token %q, got %q", first, response)
+		}
+		if n := atomic.LoadInt32(&apiRequests); n != 2 {
+			t.Errorf("Expected 2 API requests, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package oauth2client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// TokenStore persists tokens so they survive process restarts.
+//
+// Implementations must be safe for concurrent use. Load returns a nil token
+// and a nil error when no token is stored under the given key.
+type TokenStore interface {
+	Load(key string) (*Token, error)
+	Save(key string, token *Token) error
+}
+
+// TokenStoreLocker is implemented by TokenStores that are shared between
+// processes. While the lock is held, only the holder fetches a new token;
+// other processes wait and then read the token it saved.
+type TokenStoreLocker interface {
+	// Lock blocks until the caller holds an exclusive lock for key and
+	// returns a function that releases it.
+	Lock(key string) (unlock func() error, err error)
+}
+
+const (
+	tokenStoreVersion     = 1
+	tokenStoreKDFNone     = "none"
+	tokenStoreKDFPBKDF2   = "pbkdf2-sha256"
+	tokenStoreIterations  = 600000
+	tokenStoreSaltSize    = 16
+	tokenStoreFilePerm    = 0600
+	tokenStoreDerivedSize = 32
+)
+
+// tokenStoreFile is the on-disk layout of a FileTokenStore.
+// Each entry is encrypted separately with the entry key as additional data.
+type tokenStoreFile struct {
+	Version    int               `json:"version"`
+	KDF        string            `json:"kdf"`
+	Iterations int               `json:"iterations,omitempty"`
+	Salt       []byte            `json:"salt,omitempty"`
+	Entries    map[string][]byte `json:"entries"`
+}
+
+// FileTokenStore is a TokenStore that keeps tokens in a single file,
+// encrypting every entry with AES-GCM.
+//
+// The file is created with 0600 permissions and replaced atomically on every
+// write. Loading a file that is readable by group or others fails.
+//
+// FileTokenStore implements TokenStoreLocker using an advisory lock on a
+// sibling ".lock" file, so several processes can share one store and only one
+// of them refreshes an expired token at a time. On platforms without advisory
+// file locks, such as Solaris and AIX, the lock only covers the current process.
+type FileTokenStore struct {
+	path       string
+	passphrase []byte
+	key        []byte
+
+	mutex       sync.Mutex
+	derivedSalt []byte
+	derivedKey  []byte
+}
+
+// NewFileTokenStore creates a FileTokenStore whose encryption key is derived
+// from passphrase using PBKDF2-HMAC-SHA256 and a random per-file salt.
+//
+// Example:
+//
+//	store, err := oauth2client.NewFileTokenStore("/var/lib/myapp/tokens", []byte(os.Getenv("TOKEN_PASSPHRASE")))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	config.TokenStore = store
+func NewFileTokenStore(path string, passphrase []byte) (*FileTokenStore, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("token store passphrase must not be empty")
+	}
+	return &FileTokenStore{
+		path:       path,
+		passphrase: append([]byte(nil), passphrase...),
+	}, nil
+}
+
+// NewFileTokenStoreWithKey creates a FileTokenStore that uses key directly as
+// the AES key. The key must be 16, 24 or 32 bytes long.
+func NewFileTokenStoreWithKey(path string, key []byte) (*FileTokenStore, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("invalid token store key size %d", len(key))
+	}
+	return &FileTokenStore{
+		path: path,
+		key:  append([]byte(nil), key...),
+	}, nil
+}
+
+// Load returns the token stored under key, or nil if there is none.
+func (s *FileTokenStore) Load(key string) (*Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := s.readFile()
+	if err != nil || file == nil {
+		return nil, err
+	}
+
+	sealed, ok := file.Entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	plaintext, err := s.open(file, key, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode stored token: %w", err)
+	}
+	return &token, nil
+}
+
+// Save encrypts token and stores it under key, replacing any previous entry.
+func (s *FileTokenStore) Save(key string, token *Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		if file, err = s.newFile(); err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %w", err)
+	}
+	sealed, err := s.seal(file, key, plaintext)
+	if err != nil {
+		return err
+	}
+	file.Entries[key] = sealed
+
+	return s.writeFile(file)
+}
+
+// Delete removes the token stored under key, if any.
+func (s *FileTokenStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := s.readFile()
+	if err != nil || file == nil {
+		return err
+	}
+	if _, ok := file.Entries[key]; !ok {
+		return nil
+	}
+	delete(file.Entries, key)
+
+	return s.writeFile(file)
+}
+
+// Lock acquires an exclusive advisory lock on the store, blocking until it is
+// available. The lock covers the whole file, regardless of key.
+func (s *FileTokenStore) Lock(key string) (func() error, error) {
+	f, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, tokenStoreFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token store lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock token store: %w", err)
+	}
+	return func() error {
+		err := unlockFile(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// readFile reads and parses the store file. It returns nil if the file does not exist.
+func (s *FileTokenStore) readFile() (*tokenStoreFile, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token store: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat token store: %w", err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("token store %s has insecure permissions %#o, expected %#o", s.path, fi.Mode().Perm(), tokenStoreFilePerm)
+	}
+
+	var file tokenStoreFile
+	if err := json.NewDecoder(f).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode token store: %w", err)
+	}
+	if file.Version != tokenStoreVersion {
+		return nil, fmt.Errorf("unsupported token store version %d", file.Version)
+	}
+	if file.Entries == nil {
+		file.Entries = make(map[string][]byte)
+	}
+	return &file, nil
+}
+
+func (s *FileTokenStore) newFile() (*tokenStoreFile, error) {
+	file := &tokenStoreFile{
+		Version: tokenStoreVersion,
+		KDF:     tokenStoreKDFNone,
+		Entries: make(map[string][]byte),
+	}
+	if s.key == nil {
+		file.KDF = tokenStoreKDFPBKDF2
+		file.Iterations = tokenStoreIterations
+		file.Salt = make([]byte, tokenStoreSaltSize)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+	return file, nil
+}
+
+func (s *FileTokenStore) writeFile(file *tokenStoreFile) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode token store: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data, tokenStoreFilePerm); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	return nil
+}
+
+// aead returns the cipher for file, deriving the key from the passphrase if needed.
+func (s *FileTokenStore) aead(file *tokenStoreFile) (cipher.AEAD, error) {
+	var key []byte
+	switch file.KDF {
+	case tokenStoreKDFNone:
+		if s.key == nil {
+			return nil, errors.New("token store was written with a raw key, but a passphrase was supplied")
+		}
+		key = s.key
+	case tokenStoreKDFPBKDF2:
+		if s.passphrase == nil {
+			return nil, errors.New("token store was written with a passphrase, but a raw key was supplied")
+		}
+		if s.derivedKey == nil || !hmac.Equal(s.derivedSalt, file.Salt) {
+			s.derivedKey = pbkdf2SHA256(s.passphrase, file.Salt, file.Iterations, tokenStoreDerivedSize)
+			s.derivedSalt = append([]byte(nil), file.Salt...)
+		}
+		key = s.derivedKey
+	default:
+		return nil, fmt.Errorf("unsupported token store key derivation %q", file.KDF)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *FileTokenStore) seal(file *tokenStoreFile, key string, plaintext []byte) ([]byte, error) {
+	aead, err := s.aead(file)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(key)), nil
+}
+
+func (s *FileTokenStore) open(file *tokenStoreFile, key string, sealed []byte) ([]byte, error) {
+	aead, err := s.aead(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("failed to decrypt stored token: entry too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt stored token: %w", err)
+	}
+	return plaintext, nil
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 8018) with HMAC-SHA256 as the pseudorandom function.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var counter [4]byte
+	derived := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		derived = prf.Sum(derived)
+		t := derived[len(derived)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return derived[:keyLen]
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as path
+// and renames it into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// tokenStoreKey returns the key under which tokens for config are stored.
+// The key is a hash so that client identifiers are not exposed by the store.
+func tokenStoreKey(config OAuth2Config) string {
+	h := sha256.New()
+	for _, part := range []string{config.TokenURL, config.ClientID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, scope := range config.Scopes {
+		h.Write([]byte(scope))
+		h.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,244 @@
+package oauth2client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// Test vectors from RFC 7914, section 11.
+	got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("Unexpected derived key:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	token := &Token{
+		AccessToken:  "stored_access_token",
+		TokenType:    "Bearer",
+		RefreshToken: "stored_refresh_token",
+		Expiry:       time.Now().Add(time.Hour).Round(time.Second),
+	}
+
+	t.Run("Passphrase round trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens")
+		store, err := NewFileTokenStore(path, []byte("correct horse battery staple"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if loaded, err := store.Load("key"); err != nil || loaded != nil {
+			t.Fatalf("Expected no token before save, got %v, %v", loaded, err)
+		}
+		if err := store.Save("key", token); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read store file: %v", err)
+		}
+		if bytes.Contains(data, []byte(token.AccessToken)) || bytes.Contains(data, []byte(token.RefreshToken)) {
+			t.Errorf("Store file contains plaintext token: %s", data)
+		}
+
+		if runtime.GOOS != "windows" {
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat store file: %v", err)
+			}
+			if fi.Mode().Perm() != 0600 {
+				t.Errorf("Unexpected file permissions: %#o", fi.Mode().Perm())
+			}
+		}
+
+		reopened, _ := NewFileTokenStore(path, []byte("correct horse battery staple"))
+		loaded, err := reopened.Load("key")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if loaded == nil || loaded.AccessToken != token.AccessToken || loaded.RefreshToken != token.RefreshToken || !loaded.Expiry.Equal(token.Expiry) {
+			t.Errorf("Unexpected loaded token: %+v", loaded)
+		}
+
+		wrong, _ := NewFileTokenStore(path, []byte("wrong passphrase"))
+		if _, err := wrong.Load("key"); err == nil {
+			t.Error("Expected error when loading with the wrong passphrase")
+		}
+
+		if err := store.Delete("key"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if loaded, err := store.Load("key"); err != nil || loaded != nil {
+			t.Errorf("Expected no token after delete, got %v, %v", loaded, err)
+		}
+	})
+
+	t.Run("Raw key", func(t *testing.T) {
+		if _, err := NewFileTokenStoreWithKey("tokens", []byte("short")); err == nil {
+			t.Error("Expected error for invalid key size")
+		}
+
+		path := filepath.Join(t.TempDir(), "tokens")
+		store, err := NewFileTokenStoreWithKey(path, bytes.Repeat([]byte{7}, 32))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := store.Save("key", token); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		loaded, err := store.Load("key")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if loaded == nil || loaded.AccessToken != token.AccessToken {
+			t.Errorf("Unexpected loaded token: %+v", loaded)
+		}
+
+		other, _ := NewFileTokenStoreWithKey(path, bytes.Repeat([]byte{8}, 32))
+		if _, err := other.Load("key"); err == nil {
+			t.Error("Expected error when loading with the wrong key")
+		}
+	})
+
+	t.Run("Insecure permissions", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file permissions are not enforced on Windows")
+		}
+		path := filepath.Join(t.TempDir(), "tokens")
+		store, _ := NewFileTokenStoreWithKey(path, bytes.Repeat([]byte{7}, 32))
+		if err := store.Save("key", token); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatalf("Failed to chmod store file: %v", err)
+		}
+		if _, err := store.Load("key"); err == nil {
+			t.Error("Expected error for world-readable store file")
+		}
+	})
+
+	t.Run("Token manager uses stored token", func(t *testing.T) {
+		var tokenRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&tokenRequests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "fresh_access_token",
+				"token_type":    "Bearer",
+				"refresh_token": "fresh_refresh_token",
+				"expires_in":    3600,
+			})
+		}))
+		defer tokenServer.Close()
+
+		store, _ := NewFileTokenStoreWithKey(filepath.Join(t.TempDir(), "tokens"), bytes.Repeat([]byte{7}, 32))
+		config := OAuth2Config{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "test_client_id",
+			ClientSecret: "test_client_secret",
+			TokenStore:   store,
+		}
+
+		first := &tokenManager{config: config}
+		if token, err := first.getValidToken(); err != nil || token != "fresh_access_token" {
+			t.Fatalf("Unexpected token: %q, %v", token, err)
+		}
+
+		second := &tokenManager{config: config}
+		if token, err := second.getValidToken(); err != nil || token != "fresh_access_token" {
+			t.Fatalf("Unexpected token: %q, %v", token, err)
+		}
+		if second.refreshValue != "fresh_refresh_token" {
+			t.Errorf("Unexpected refresh token: %q", second.refreshValue)
+		}
+		if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+			t.Errorf("Expected 1 token request, got %d", n)
+		}
+	})
+
+	t.Run("Shared between processes", func(t *testing.T) {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+			t.Skip("file locking is not exercised on this platform")
+		}
+
+		var tokenRequests int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tokenRequests, 1)
+			time.Sleep(100 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "shared_access_token_" + string(rune('0'+n)),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		}))
+		defer tokenServer.Close()
+
+		path := filepath.Join(t.TempDir(), "tokens")
+		newManager := func() *tokenManager {
+			// A separate store per manager stands in for a separate process.
+			store, _ := NewFileTokenStoreWithKey(path, bytes.Repeat([]byte{7}, 32))
+			return &tokenManager{config: OAuth2Config{
+				TokenURL:     tokenServer.URL,
+				ClientID:     "test_client_id",
+				ClientSecret: "test_client_secret",
+				TokenStore:   store,
+			}}
+		}
+
+		var wg sync.WaitGroup
+		tokens := make([]string, 8)
+		for i := range tokens {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				token, err := newManager().getValidToken()
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				tokens[i] = token
+			}(i)
+		}
+		wg.Wait()
+
+		if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+			t.Errorf("Expected 1 token request, got %d", n)
+		}
+		for _, token := range tokens {
+			if token != "shared_access_token_1" {
+				t.Errorf("Unexpected token: %q", token)
+			}
+		}
+
+		// A token rejected by the server is replaced once, and the replacement is
+		// picked up by other processes instead of being refreshed again.
+		first, second := newManager(), newManager()
+		first.getValidToken()
+		second.getValidToken()
+		if err := first.refreshStaleToken("shared_access_token_1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := second.refreshStaleToken("shared_access_token_1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if first.accessToken != "shared_access_token_2" || second.accessToken != "shared_access_token_2" {
+			t.Errorf("Unexpected tokens after refresh: %q, %q", first.accessToken, second.accessToken)
+		}
+		if n := atomic.LoadInt32(&tokenRequests); n != 2 {
+			t.Errorf("Expected 2 token requests, got %d", n)
+		}
+	})
+}
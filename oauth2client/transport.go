@@ -0,0 +1,116 @@
+package oauth2client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that authenticates requests before
+// passing them to Base. It brings the token management of APIClient to any
+// library that accepts an *http.Client.
+//
+// If the server responds with 401 Unauthorized and Auth can refresh its
+// credentials, the request is sent once more with fresh credentials, provided
+// its body can be replayed through Request.GetBody.
+type Transport struct {
+	// Auth adds credentials to each request. If nil, requests are sent unchanged.
+	Auth Authenticator
+
+	// Base sends the authenticated requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// NewTransport returns a Transport that authenticates requests with OAuth2
+// tokens obtained using config and sends them through base. Token requests
+// are sent through base as well.
+//
+// Parameters:
+//   - config: The OAuth2 configuration including token URL, client credentials, and scopes.
+//     If nil, requests are sent without authentication.
+//   - base: The RoundTripper for API and token requests. If nil, http.DefaultTransport is used.
+//
+// Returns:
+//   - *Transport: A new instance of Transport.
+//
+// Example:
+//
+//	httpClient := &http.Client{Transport: oauth2client.NewTransport(&config, nil)}
+//	sdk := thirdparty.NewClient(httpClient)
+func NewTransport(config *OAuth2Config, base http.RoundTripper) *Transport {
+	if config == nil {
+		return &Transport{Base: base}
+	}
+	tm := &tokenManager{config: *config}
+	if base != nil {
+		tm.httpClient = &http.Client{Transport: base}
+	}
+	return &Transport{
+		Auth: TokenSourceAuth{Source: tm},
+		Base: base,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. The original request is not modified.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return sendAuthenticated(req.Clone(req.Context()), t.Auth, t.base().RoundTrip)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// sendAuthenticated adds credentials from auth to req and sends it with send.
+// If the server rejects the credentials with 401 Unauthorized and auth can
+// refresh them, the request is sent once more with the new credentials.
+func sendAuthenticated(req *http.Request, auth Authenticator, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if auth == nil {
+		return send(req)
+	}
+
+	if err := auth.Authenticate(req); err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	refresher, ok := auth.(credentialRefresher)
+	if resp.StatusCode != http.StatusUnauthorized || !ok {
+		return resp, nil
+	}
+
+	// Token might have expired, try refreshing and calling again
+	refreshed, err := refresher.refreshCredentials(req)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if !refreshed {
+		return resp, nil
+	}
+	retry, err := rewindRequest(req)
+	if err != nil {
+		// The body cannot be sent again, so report the original response.
+		return resp, nil
+	}
+	drainAndClose(resp.Body)
+
+	if err := auth.Authenticate(retry); err != nil {
+		closeRequestBody(retry)
+		return nil, err
+	}
+	return send(retry)
+}
+
+// closeRequestBody closes the body of a request that will not be sent.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,74 @@
+package oauth2client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "transport_token_" + string(rune('0'+n)),
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the second token is accepted.
+		if r.Header.Get("Authorization") != "Bearer transport_token_2" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer apiServer.Close()
+
+	config := OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "test_client_id",
+		ClientSecret: "test_client_secret",
+	}
+	httpClient := &http.Client{Transport: NewTransport(&config, http.DefaultTransport)}
+
+	req, _ := http.NewRequest("POST", apiServer.URL, strings.NewReader("payload"))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, body)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("RoundTrip modified the original request")
+	}
+	if n := atomic.LoadInt32(&tokenRequests); n != 2 {
+		t.Errorf("Expected 2 token requests, got %d", n)
+	}
+
+	// Without a config, requests are sent unauthenticated.
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+	}))
+	defer plainServer.Close()
+	plainClient := &http.Client{Transport: NewTransport(nil, nil)}
+	plainResp, err := plainClient.Get(plainServer.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	plainResp.Body.Close()
+}
@@ -0,0 +1,455 @@
+package oauth2client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	tusVersion       = "1.0.0"
+	defaultChunkSize = 5 << 20
+	defaultResyncs   = 3
+)
+
+// UploadURLStore persists the URLs of unfinished resumable uploads, so that a
+// restarted process can resume them instead of starting over.
+//
+// Implementations must be safe for concurrent use. Load returns an empty
+// string and a nil error when no URL is stored under the given fingerprint.
+type UploadURLStore interface {
+	Load(fingerprint string) (string, error)
+	Save(fingerprint, uploadURL string) error
+	Delete(fingerprint string) error
+}
+
+// TusUploader uploads files with the tus 1.0 resumable upload protocol
+// (https://tus.io/protocols/resumable-upload), using the creation and
+// termination extensions. All requests are authenticated by Client.
+//
+// Example:
+//
+//	store, _ := oauth2client.NewFileUploadURLStore("/var/lib/myapp/uploads.json")
+//	uploader := &oauth2client.TusUploader{Client: client, Endpoint: "/files", Store: store}
+//	upload, err := oauth2client.NewTusFileUpload("./dataset.tar")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer upload.Close()
+//	if err := uploader.Upload(ctx, upload); err != nil {
+//		log.Fatal(err) // run again to resume
+//	}
+//	fmt.Println("Uploaded to", upload.URL)
+type TusUploader struct {
+	// Client sends the requests.
+	Client *APIClient
+
+	// Endpoint is the creation URL, as a path relative to the client's base URL.
+	Endpoint string
+
+	// ChunkSize is the maximum size of a PATCH request. Defaults to 5 MiB.
+	ChunkSize int64
+
+	// Store, if set, keeps the upload URLs of unfinished uploads.
+	Store UploadURLStore
+
+	// MaxResyncs is the number of consecutive failed PATCH requests after
+	// which Upload gives up. After each failure, Upload waits for the backoff
+	// of the client's RetryPolicy, or of DefaultRetryPolicy, and reads the
+	// offset again from the server. Defaults to 3.
+	MaxResyncs int
+}
+
+// TusUpload is the content and state of a resumable upload.
+type TusUpload struct {
+	// Content is read at the offsets the server asks for.
+	Content io.ReaderAt
+
+	// Size is the total length of the upload in bytes.
+	Size int64
+
+	// Fingerprint identifies the upload in the UploadURLStore. Uploads without
+	// a fingerprint are not persisted.
+	Fingerprint string
+
+	// Metadata is sent in the Upload-Metadata header when the upload is created.
+	Metadata map[string]string
+
+	// URL is the upload URL. It is set once the upload is created or resumed.
+	URL string
+
+	// Offset is the number of bytes the server has received.
+	Offset int64
+
+	closer io.Closer
+}
+
+// NewTusFileUpload returns a TusUpload for the file at path. Its fingerprint
+// combines the absolute path, size and modification time, and its metadata
+// contains the file name. The upload must be closed after use.
+func NewTusFileUpload(path string) (*TusUpload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	return &TusUpload{
+		Content:     f,
+		Size:        fi.Size(),
+		Fingerprint: fmt.Sprintf("%s-%d-%d", absPath, fi.Size(), fi.ModTime().UnixNano()),
+		Metadata:    map[string]string{"filename": fi.Name()},
+		closer:      f,
+	}, nil
+}
+
+// Close closes the file of an upload created by NewTusFileUpload.
+func (u *TusUpload) Close() error {
+	if u.closer == nil {
+		return nil
+	}
+	return u.closer.Close()
+}
+
+// Upload sends the content of upload, resuming a stored upload URL if the
+// server still knows it and creating a new upload otherwise. When it returns
+// an error, the upload URL stays in the store so that a later call resumes
+// where this one stopped.
+func (t *TusUploader) Upload(ctx context.Context, upload *TusUpload) error {
+	if err := t.resumeOrCreate(ctx, upload); err != nil {
+		return err
+	}
+
+	chunkSize := t.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	maxResyncs := t.MaxResyncs
+	if maxResyncs <= 0 {
+		maxResyncs = defaultResyncs
+	}
+
+	policy := t.Client.retryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy().withDefaults()
+	}
+
+	failures := 0
+	for upload.Offset < upload.Size {
+		err := t.patch(ctx, upload, chunkSize)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if failures++; failures > maxResyncs {
+			return err
+		}
+		wait, _ := policy.delay(failures, nil)
+		if sleepErr := sleepContext(ctx, wait); sleepErr != nil {
+			return err
+		}
+		if offset, headErr := t.offset(ctx, upload.URL); headErr == nil {
+			upload.Offset = offset
+		}
+	}
+
+	if t.Store != nil && upload.Fingerprint != "" {
+		if err := t.Store.Delete(upload.Fingerprint); err != nil {
+			return fmt.Errorf("failed to delete upload URL: %w", err)
+		}
+	}
+	return nil
+}
+
+// Terminate deletes an unfinished upload on the server and from the store.
+func (t *TusUploader) Terminate(ctx context.Context, upload *TusUpload) error {
+	uploadURL := upload.URL
+	if uploadURL == "" && t.Store != nil && upload.Fingerprint != "" {
+		stored, err := t.Store.Load(upload.Fingerprint)
+		if err != nil {
+			return fmt.Errorf("failed to load upload URL: %w", err)
+		}
+		uploadURL = stored
+	}
+	if uploadURL == "" {
+		return errors.New("upload has not been created")
+	}
+
+	_, err := t.callUploadURL(ctx, HttpDelete, uploadURL, nil, tusHeaders(nil),
+		WithCallStatusPolicy(AcceptStatusCodes(http.StatusNoContent, http.StatusNotFound, http.StatusGone)))
+	if err != nil {
+		return err
+	}
+	if t.Store != nil && upload.Fingerprint != "" {
+		if err := t.Store.Delete(upload.Fingerprint); err != nil {
+			return fmt.Errorf("failed to delete upload URL: %w", err)
+		}
+	}
+	upload.URL = ""
+	upload.Offset = 0
+	return nil
+}
+
+// resumeOrCreate sets the URL and offset of upload, from the store if the
+// server still has the upload, or by creating a new one.
+func (t *TusUploader) resumeOrCreate(ctx context.Context, upload *TusUpload) error {
+	if upload.URL == "" && t.Store != nil && upload.Fingerprint != "" {
+		stored, err := t.Store.Load(upload.Fingerprint)
+		if err != nil {
+			return fmt.Errorf("failed to load upload URL: %w", err)
+		}
+		upload.URL = stored
+	}
+
+	if upload.URL != "" {
+		offset, err := t.offset(ctx, upload.URL)
+		if err == nil {
+			upload.Offset = offset
+			return nil
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			return err
+		}
+		switch apiErr.StatusCode {
+		case http.StatusNotFound, http.StatusGone, http.StatusForbidden:
+			// The server no longer knows the upload; start over.
+			upload.URL = ""
+		default:
+			return err
+		}
+	}
+
+	headers := tusHeaders(map[string]string{"Upload-Length": strconv.FormatInt(upload.Size, 10)})
+	if metadata := encodeTusMetadata(upload.Metadata); metadata != "" {
+		headers["Upload-Metadata"] = metadata
+	}
+	response, err := t.Client.CallAPIWithResponse(ctx, HttpPost, t.Endpoint, nil, headers,
+		WithCallStatusPolicy(AcceptStatusCodes(http.StatusCreated)))
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	location := response.Header.Get("Location")
+	if location == "" {
+		return errors.New("failed to create upload: response has no Location header")
+	}
+	uploadURL, err := t.uploadURL(response.URL, location)
+	if err != nil {
+		return err
+	}
+
+	upload.URL = uploadURL
+	upload.Offset = 0
+	if t.Store != nil && upload.Fingerprint != "" {
+		if err := t.Store.Save(upload.Fingerprint, uploadURL); err != nil {
+			return fmt.Errorf("failed to save upload URL: %w", err)
+		}
+	}
+	return nil
+}
+
+// uploadURL resolves location against the creation URL. The upload URL must
+// be on the same host as the API, so that credentials are not sent elsewhere.
+func (t *TusUploader) uploadURL(creationURL *url.URL, location string) (string, error) {
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: invalid Location %q: %w", location, err)
+	}
+	resolved := creationURL.ResolveReference(ref)
+	if !strings.EqualFold(resolved.Host, creationURL.Host) || !strings.EqualFold(resolved.Scheme, creationURL.Scheme) {
+		return "", fmt.Errorf("failed to create upload: upload URL %s is not on %s://%s", resolved, creationURL.Scheme, creationURL.Host)
+	}
+	return resolved.String(), nil
+}
+
+// callUploadURL sends a request to an upload URL. The client only sends its
+// credentials to an upload URL on the same scheme and host as the endpoint.
+func (t *TusUploader) callUploadURL(ctx context.Context, method HttpMethod, uploadURL string, body interface{}, headers map[string]string, opts ...CallOption) (*Response, error) {
+	endpoint, err := url.Parse(t.Client.baseURL + t.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload endpoint: %w", err)
+	}
+	target, err := url.Parse(uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload URL %q: %w", uploadURL, err)
+	}
+	target = endpoint.ResolveReference(target)
+	if !strings.EqualFold(target.Host, endpoint.Host) || !strings.EqualFold(target.Scheme, endpoint.Scheme) {
+		return nil, fmt.Errorf("upload URL %s is not on %s://%s", target, endpoint.Scheme, endpoint.Host)
+	}
+	return t.Client.callURL(ctx, method, target.String(), body, headers, opts...)
+}
+
+// offset asks the server how many bytes of the upload it has received.
+func (t *TusUploader) offset(ctx context.Context, uploadURL string) (int64, error) {
+	response, err := t.callUploadURL(ctx, HttpHead, uploadURL, nil, tusHeaders(map[string]string{"Cache-Control": "no-store"}),
+		WithCallStatusPolicy(AcceptStatusCodes(http.StatusOK, http.StatusNoContent)))
+	if err != nil {
+		return 0, err
+	}
+	return parseUploadOffset(response.Header)
+}
+
+// patch sends the next chunk of upload and advances its offset.
+func (t *TusUploader) patch(ctx context.Context, upload *TusUpload, chunkSize int64) error {
+	offset := upload.Offset
+	length := upload.Size - offset
+	if length > chunkSize {
+		length = chunkSize
+	}
+	body := ReplayableBody(func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(upload.Content, offset, length)), nil
+	}, "application/offset+octet-stream", length)
+
+	headers := tusHeaders(map[string]string{"Upload-Offset": strconv.FormatInt(offset, 10)})
+	response, err := t.callUploadURL(ctx, HttpPatch, upload.URL, body, headers,
+		WithCallStatusPolicy(AcceptStatusCodes(http.StatusNoContent)))
+	if err != nil {
+		return fmt.Errorf("failed to upload chunk at offset %d: %w", offset, err)
+	}
+	newOffset, err := parseUploadOffset(response.Header)
+	if err != nil {
+		return err
+	}
+	if newOffset <= offset || newOffset > upload.Size {
+		return fmt.Errorf("failed to upload chunk at offset %d: server reported offset %d", offset, newOffset)
+	}
+	upload.Offset = newOffset
+	return nil
+}
+
+// tusHeaders returns headers with the Tus-Resumable header added.
+func tusHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers["Tus-Resumable"] = tusVersion
+	return headers
+}
+
+func parseUploadOffset(header http.Header) (int64, error) {
+	offset, err := strconv.ParseInt(header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid Upload-Offset header %q", header.Get("Upload-Offset"))
+	}
+	return offset, nil
+}
+
+// encodeTusMetadata encodes metadata for the Upload-Metadata header, with
+// keys in sorted order.
+func encodeTusMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// FileUploadURLStore is an UploadURLStore that keeps upload URLs in a JSON
+// file, created with 0600 permissions and replaced atomically on every write.
+type FileUploadURLStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileUploadURLStore creates a FileUploadURLStore backed by the file at path.
+func NewFileUploadURLStore(path string) (*FileUploadURLStore, error) {
+	if path == "" {
+		return nil, errors.New("upload URL store path must not be empty")
+	}
+	return &FileUploadURLStore{path: path}, nil
+}
+
+// Load returns the upload URL stored under fingerprint, or "" if there is none.
+func (s *FileUploadURLStore) Load(fingerprint string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return entries[fingerprint], nil
+}
+
+// Save stores uploadURL under fingerprint.
+func (s *FileUploadURLStore) Save(fingerprint, uploadURL string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	entries[fingerprint] = uploadURL
+	return s.write(entries)
+}
+
+// Delete removes the upload URL stored under fingerprint, if any.
+func (s *FileUploadURLStore) Delete(fingerprint string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[fingerprint]; !ok {
+		return nil
+	}
+	delete(entries, fingerprint)
+	return s.write(entries)
+}
+
+func (s *FileUploadURLStore) read() (map[string]string, error) {
+	entries := make(map[string]string)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload URL store: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse upload URL store: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *FileUploadURLStore) write(entries map[string]string) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upload URL store: %w", err)
+	}
+	return nil
+}
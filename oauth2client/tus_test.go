@@ -0,0 +1,264 @@
+package oauth2client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tusTestServer is a minimal tus 1.0 server keeping uploads in memory.
+type tusTestServer struct {
+	mutex    sync.Mutex
+	uploads  map[string]*bytes.Buffer
+	lengths  map[string]int64
+	metadata map[string]string
+	created  int
+	patches  []int64
+	failAt   int64
+}
+
+func (s *tusTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.Header.Get("Tus-Resumable") != "1.0.0" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	w.Header().Set("Tus-Resumable", "1.0.0")
+
+	if r.Method == http.MethodPost && r.URL.Path == "/files" {
+		s.created++
+		id := strconv.Itoa(s.created)
+		length, _ := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		s.uploads[id] = &bytes.Buffer{}
+		s.lengths[id] = length
+		s.metadata[id] = r.Header.Get("Upload-Metadata")
+		w.Header().Set("Location", "/files/"+id)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/files/")
+	upload, ok := s.uploads[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.Itoa(upload.Len()))
+		w.Header().Set("Upload-Length", strconv.FormatInt(s.lengths[id], 10))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPatch:
+		offset, _ := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		s.patches = append(s.patches, offset)
+		if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if offset != int64(upload.Len()) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if s.failAt > 0 && offset >= s.failAt {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(upload, r.Body)
+		w.Header().Set("Upload-Offset", strconv.Itoa(upload.Len()))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestTusUploader(t *testing.T) {
+	newServer := func() (*tusTestServer, *httptest.Server) {
+		s := &tusTestServer{uploads: map[string]*bytes.Buffer{}, lengths: map[string]int64{}, metadata: map[string]string{}}
+		return s, httptest.NewServer(s)
+	}
+
+	dir := t.TempDir()
+	content := make([]byte, 2500)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	path := filepath.Join(dir, "dataset.bin")
+	os.WriteFile(path, content, 0600)
+
+	t.Run("Upload in chunks", func(t *testing.T) {
+		server, apiServer := newServer()
+		defer apiServer.Close()
+
+		upload, err := NewTusFileUpload(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer upload.Close()
+
+		uploader := &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files", ChunkSize: 1000}
+		if err := uploader.Upload(context.Background(), upload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if upload.URL != apiServer.URL+"/files/1" || upload.Offset != int64(len(content)) {
+			t.Errorf("Unexpected upload state: %s at %d", upload.URL, upload.Offset)
+		}
+		if !bytes.Equal(server.uploads["1"].Bytes(), content) {
+			t.Error("Uploaded content does not match")
+		}
+		if fmt.Sprint(server.patches) != "[0 1000 2000]" {
+			t.Errorf("Unexpected PATCH offsets: %v", server.patches)
+		}
+		if want := "filename " + base64.StdEncoding.EncodeToString([]byte("dataset.bin")); server.metadata["1"] != want {
+			t.Errorf("Unexpected metadata: %q", server.metadata["1"])
+		}
+	})
+
+	t.Run("Resume after restart", func(t *testing.T) {
+		server, apiServer := newServer()
+		defer apiServer.Close()
+		server.failAt = 1000
+
+		store, _ := NewFileUploadURLStore(filepath.Join(dir, "uploads.json"))
+		newUploader := func() *TusUploader {
+			return &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files", ChunkSize: 1000, Store: store, MaxResyncs: 1}
+		}
+
+		first, _ := NewTusFileUpload(path)
+		if err := newUploader().Upload(context.Background(), first); err == nil {
+			t.Fatal("Expected the interrupted upload to fail")
+		}
+		first.Close()
+		if stored, _ := store.Load(first.Fingerprint); stored != apiServer.URL+"/files/1" {
+			t.Fatalf("Expected the upload URL to be stored, got %q", stored)
+		}
+
+		// A new process resumes from the stored URL at the server's offset.
+		server.mutex.Lock()
+		server.failAt = 0
+		server.patches = nil
+		server.mutex.Unlock()
+
+		second, _ := NewTusFileUpload(path)
+		defer second.Close()
+		if err := newUploader().Upload(context.Background(), second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if server.created != 1 {
+			t.Errorf("Expected the upload to be resumed, %d uploads created", server.created)
+		}
+		if fmt.Sprint(server.patches) != "[1000 2000]" || !bytes.Equal(server.uploads["1"].Bytes(), content) {
+			t.Errorf("Unexpected resumed upload: %v", server.patches)
+		}
+		if stored, _ := store.Load(second.Fingerprint); stored != "" {
+			t.Errorf("Expected the finished upload to be removed from the store, got %q", stored)
+		}
+	})
+
+	t.Run("Resyncs back off", func(t *testing.T) {
+		server, apiServer := newServer()
+		defer apiServer.Close()
+		server.failAt = 1000
+
+		client, _ := NewAPIClientWithOptions(apiServer.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 1, InitialBackoff: 30 * time.Millisecond}))
+		uploader := &TusUploader{Client: client, Endpoint: "/files", ChunkSize: 1000, MaxResyncs: 2}
+		upload := &TusUpload{Content: bytes.NewReader(content), Size: int64(len(content))}
+		start := time.Now()
+		if err := uploader.Upload(context.Background(), upload); err == nil {
+			t.Fatal("Expected the upload to fail")
+		}
+		// Two resyncs wait 30ms and 60ms.
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("Expected resyncs to back off, took %v", elapsed)
+		}
+		if fmt.Sprint(server.patches) != "[0 1000 1000 1000]" {
+			t.Errorf("Unexpected patches: %v", server.patches)
+		}
+	})
+
+	t.Run("Expired upload is recreated", func(t *testing.T) {
+		server, apiServer := newServer()
+		defer apiServer.Close()
+
+		store, _ := NewFileUploadURLStore(filepath.Join(dir, "expired.json"))
+		upload := &TusUpload{Content: bytes.NewReader(content), Size: int64(len(content)), Fingerprint: "dataset"}
+		store.Save(upload.Fingerprint, apiServer.URL+"/files/unknown")
+
+		uploader := &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files", Store: store}
+		if err := uploader.Upload(context.Background(), upload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if server.created != 1 || !bytes.Equal(server.uploads["1"].Bytes(), content) {
+			t.Errorf("Expected a new upload, got %d", server.created)
+		}
+	})
+
+	t.Run("Terminate", func(t *testing.T) {
+		server, apiServer := newServer()
+		defer apiServer.Close()
+		server.failAt = 1000
+
+		store, _ := NewFileUploadURLStore(filepath.Join(dir, "terminate.json"))
+		upload := &TusUpload{Content: bytes.NewReader(content), Size: int64(len(content)), Fingerprint: "dataset"}
+		uploader := &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files", ChunkSize: 1000, Store: store, MaxResyncs: 1}
+		uploader.Upload(context.Background(), upload)
+
+		if err := uploader.Terminate(context.Background(), &TusUpload{Fingerprint: "dataset"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(server.uploads) != 0 {
+			t.Error("Expected the upload to be deleted on the server")
+		}
+		if stored, _ := store.Load("dataset"); stored != "" {
+			t.Errorf("Expected the upload URL to be deleted, got %q", stored)
+		}
+	})
+
+	t.Run("Upload URL on another host", func(t *testing.T) {
+		apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "https://attacker.example/files/1")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer apiServer.Close()
+
+		upload := &TusUpload{Content: bytes.NewReader(content), Size: int64(len(content))}
+		uploader := &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files"}
+		if err := uploader.Upload(context.Background(), upload); err == nil {
+			t.Error("Expected error for upload URL on another host")
+		}
+	})
+
+	t.Run("Stored upload URL on another host", func(t *testing.T) {
+		var requests int32
+		otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+		}))
+		defer otherServer.Close()
+
+		_, apiServer := newServer()
+		defer apiServer.Close()
+
+		upload := &TusUpload{Content: bytes.NewReader(content), Size: int64(len(content)), URL: otherServer.URL + "/files/1"}
+		uploader := &TusUploader{Client: NewAPIClient(nil, apiServer.URL), Endpoint: "/files"}
+		if err := uploader.Upload(context.Background(), upload); err == nil {
+			t.Error("Expected error for upload URL on another host")
+		}
+		if n := atomic.LoadInt32(&requests); n != 0 {
+			t.Errorf("Expected no request to another host, got %d", n)
+		}
+	})
+}